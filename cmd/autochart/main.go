@@ -0,0 +1,102 @@
+// Command autochart writes a draft chart for an audio file.
+//
+// It detects the tempo and the percussive onsets of the track and places notes
+// on the lane of the drum they sound like: kick on left, snare on down, hi-hat
+// on up and cymbal on right. The output follows the song JSON format of
+// assets/songs and is meant to be cleaned up in the chart editor.
+//
+// Usage:
+//
+//	go run ./cmd/autochart -o assets/songs/my-song.json assets/audio/my-song.ogg
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
+	gameautochart "github.com/leandroatallah/drummer/internal/game/autochart"
+)
+
+func main() {
+	output := flag.String("o", "", "output file (default: stdout)")
+	title := flag.String("title", "", "song title (default: file name)")
+	bpm := flag.Float64("bpm", 0, "use this tempo instead of estimating it")
+	subdivision := flag.Int("grid", 4, "notes per beat to snap onsets to")
+	threshold := flag.Float64("threshold", gameautochart.DefaultOptions().Threshold, "onset threshold, lower detects more onsets")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: autochart [flags] <audio file>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	samples, err := decode(path)
+	if err != nil {
+		log.Fatalf("failed to decode %s: %v", path, err)
+	}
+
+	opts := gameautochart.DefaultOptions()
+	opts.Bpm = *bpm
+	opts.Threshold = *threshold
+	analysis := gameautochart.Analyze(samples, audiomanager.SampleRate, opts)
+
+	name := filepath.Base(path)
+	if *title == "" {
+		*title = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	song := gameautochart.BuildSong(analysis, *title, name, *subdivision)
+
+	data, err := json.MarshalIndent(song, "", "  ")
+	if err != nil {
+		log.Fatalf("failed to encode chart: %v", err)
+	}
+	data = append(data, '\n')
+
+	if *output == "" {
+		os.Stdout.Write(data)
+	} else if err := os.WriteFile(*output, data, 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", *output, err)
+	}
+
+	log.Printf("%s: %d BPM, %d notes", name, song.Bpm, len(song.Notes))
+}
+
+// decode reads an audio file into mono samples at the audio manager sample
+// rate.
+func decode(path string) ([]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := audiomanager.Decode(path, data)
+	if err != nil {
+		return nil, err
+	}
+	pcm, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	// The decoders output 16-bit little endian stereo frames.
+	samples := make([]float64, len(pcm)/4)
+	for i := range samples {
+		left := int16(binary.LittleEndian.Uint16(pcm[i*4:]))
+		right := int16(binary.LittleEndian.Uint16(pcm[i*4+2:]))
+		samples[i] = (float64(left) + float64(right)) / 2 / 32768
+	}
+	return samples, nil
+}
@@ -1,6 +1,10 @@
 package config
 
-import "image/color"
+import (
+	"image/color"
+	"sync"
+	"time"
+)
 
 // TODO: Use a env file
 const (
@@ -8,6 +12,8 @@ const (
 	ScreenHeight  = 144
 	Unit          = 16
 	DefaultVolume = 0.5
+	// WindowScale is how many window pixels a screen pixel takes.
+	WindowScale = 6
 )
 
 // TODO: Move this specifc color config to game module
@@ -15,7 +21,8 @@ var darkOlive = color.RGBA{59, 74, 44, 255}
 var mediumOlive = color.RGBA{111, 127, 75, 255}
 var lightOlive = color.RGBA{157, 179, 107, 255}
 
-// TODO: Move this specifc color config to game module
+// ColorsConfig are tones of the palette the assets are drawn with. The color
+// theme of the game recolors them, with the assets, on the whole frame.
 type ColorsConfig struct {
 	Light  color.RGBA
 	Medium color.RGBA
@@ -57,11 +64,40 @@ type AppConfig struct {
 	Colors       ColorsConfig
 
 	DefaultVolume float64
+	// MusicVolume and SFXVolume scale the music and the sound effects, from 0
+	// to 1.
+	MusicVolume float64
+	SFXVolume   float64
+	// ScrollSpeed multiplies the scroll speed of the charts.
+	ScrollSpeed float64
+	WindowScale int
+	Fullscreen  bool
+	// AudioOffset is how late the player hears the music, and VisualOffset how
+	// late they see a frame.
+	AudioOffset  time.Duration
+	VisualOffset time.Duration
 
 	MainFontFace string
 }
 
-var cfg AppConfig
+// Overrides are the player settings that replace the defaults of AppConfig.
+// Nil fields keep the default.
+type Overrides struct {
+	MusicVolume  *float64
+	SFXVolume    *float64
+	ScrollSpeed  *float64
+	WindowScale  *int
+	Fullscreen   *bool
+	AudioOffset  *time.Duration
+	VisualOffset *time.Duration
+}
+
+var (
+	cfg AppConfig
+	// overridesMu guards overrides, so Get can be called from any goroutine.
+	overridesMu sync.RWMutex
+	overrides   Overrides
+)
 
 func init() {
 	defaultPhysics := PhysicsConfig{
@@ -91,9 +127,46 @@ func init() {
 		Colors:       defaultColors,
 
 		DefaultVolume: DefaultVolume,
+		MusicVolume:   DefaultVolume,
+		SFXVolume:     DefaultVolume,
+		ScrollSpeed:   1,
+		WindowScale:   WindowScale,
 	}
 }
 
+// SetOverrides replaces the player settings merged by Get.
+func SetOverrides(o Overrides) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	overrides = o
+}
+
+// Get returns the config, with the overrides over the defaults.
 func Get() AppConfig {
-	return cfg
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+
+	c := cfg
+	if overrides.MusicVolume != nil {
+		c.MusicVolume = *overrides.MusicVolume
+	}
+	if overrides.SFXVolume != nil {
+		c.SFXVolume = *overrides.SFXVolume
+	}
+	if overrides.ScrollSpeed != nil {
+		c.ScrollSpeed = *overrides.ScrollSpeed
+	}
+	if overrides.WindowScale != nil {
+		c.WindowScale = *overrides.WindowScale
+	}
+	if overrides.Fullscreen != nil {
+		c.Fullscreen = *overrides.Fullscreen
+	}
+	if overrides.AudioOffset != nil {
+		c.AudioOffset = *overrides.AudioOffset
+	}
+	if overrides.VisualOffset != nil {
+		c.VisualOffset = *overrides.VisualOffset
+	}
+	return c
 }
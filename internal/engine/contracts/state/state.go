@@ -0,0 +1,10 @@
+package state
+
+// GameStateEnum identifies a state of the game, like playing or paused.
+type GameStateEnum int
+
+// StateManager switches the game between its states.
+type StateManager interface {
+	SetState(stateID GameStateEnum) error
+	State() GameStateEnum
+}
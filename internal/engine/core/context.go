@@ -5,11 +5,13 @@ import (
 
 	"github.com/leandroatallah/drummer/internal/engine/actors"
 	"github.com/leandroatallah/drummer/internal/engine/contracts/navigation"
+	"github.com/leandroatallah/drummer/internal/engine/contracts/state"
 	"github.com/leandroatallah/drummer/internal/engine/core/levels"
 	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
 	"github.com/leandroatallah/drummer/internal/engine/systems/datamanager"
 	"github.com/leandroatallah/drummer/internal/engine/systems/imagemanager"
 	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	"github.com/leandroatallah/drummer/internal/engine/systems/savemanager"
 	"github.com/leandroatallah/drummer/internal/engine/systems/speech"
 )
 
@@ -22,9 +24,11 @@ type AppContext struct {
 	AudioManager          *audiomanager.AudioManager
 	ImageManager          *imagemanager.ImageManager
 	DataManager           *datamanager.Manager
+	SaveManager           *savemanager.Manager
 	DialogueManager       *speech.Manager
 	ActorManager          *actors.Manager
 	SceneManager          navigation.SceneManager
+	StateManager          state.StateManager
 	LevelManager          *levels.Manager
 	PlayerMovementBlocked bool
 	Assets                fs.FS
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"strings"
@@ -14,11 +15,21 @@ import (
 	"golang.org/x/image/font"
 )
 
+// ScreenFilter post-processes each frame before it reaches the screen.
+type ScreenFilter interface {
+	Apply(dst, src *ebiten.Image)
+}
+
 type Game struct {
 	AppContext    *core.AppContext
 	state         state.GameState
+	stateID       state.GameStateEnum
+	stateFactory  state.StateFactory
 	debugVisible  bool
 	debugFontFace font.Face
+	// filter and its frame are set with SetScreenFilter.
+	filter ScreenFilter
+	frame  *ebiten.Image
 }
 
 func NewGame(ctx *core.AppContext) *Game {
@@ -35,6 +46,11 @@ func (g *Game) Update() error {
 	// First, update the input manager
 	g.AppContext.InputManager.Update()
 
+	// Move the audio buses being ducked
+	if g.AppContext.AudioManager != nil {
+		g.AppContext.AudioManager.Update()
+	}
+
 	// Update Dialogue Manager
 	if g.AppContext.DialogueManager != nil {
 		g.AppContext.DialogueManager.Update()
@@ -46,11 +62,13 @@ func (g *Game) Update() error {
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
-	g.AppContext.SceneManager.Draw(screen)
-
-	// Draw Dialogue Manager
-	if g.AppContext.DialogueManager != nil {
-		g.AppContext.DialogueManager.Draw(screen)
+	if g.filter != nil {
+		// The scenes draw on the frame, which the filter puts on the screen.
+		g.frame.Clear()
+		g.drawScene(g.frame)
+		g.filter.Apply(screen, g.frame)
+	} else {
+		g.drawScene(screen)
 	}
 
 	if g.debugVisible {
@@ -72,18 +90,49 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	}
 }
 
+func (g *Game) drawScene(screen *ebiten.Image) {
+	g.AppContext.SceneManager.Draw(screen)
+
+	// Draw Dialogue Manager
+	if g.AppContext.DialogueManager != nil {
+		g.AppContext.DialogueManager.Draw(screen)
+	}
+}
+
+// SetScreenFilter makes every frame go through a filter, like a palette swap.
+func (g *Game) SetScreenFilter(filter ScreenFilter) {
+	g.filter = filter
+	if g.frame == nil {
+		g.frame = ebiten.NewImage(config.Get().ScreenWidth, config.Get().ScreenHeight)
+	}
+}
+
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 	return config.Get().ScreenWidth, config.Get().ScreenHeight
 }
 
+func (g *Game) SetStateFactory(factory state.StateFactory) {
+	g.stateFactory = factory
+}
+
 func (g *Game) SetState(stateID state.GameStateEnum) error {
-	// state, err := state.NewGameState(stateID, g.AppContext)
-	// if err != nil {
-	// 	return err
-	// }
-	//
-	// g.state = state
-	// g.state.OnStart()
+	if g.stateFactory == nil {
+		return errors.New("no state factory set")
+	}
+
+	s, err := g.stateFactory.Create(stateID)
+	if err != nil {
+		return err
+	}
+
+	g.state = s
+	g.stateID = stateID
+	g.state.OnStart()
 
 	return nil
 }
+
+// State returns the current game state.
+func (g *Game) State() state.GameStateEnum {
+	return g.stateID
+}
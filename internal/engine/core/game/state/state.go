@@ -1,6 +1,7 @@
 package state
 
 import (
+	contractstate "github.com/leandroatallah/drummer/internal/engine/contracts/state"
 	"github.com/leandroatallah/drummer/internal/engine/core"
 )
 
@@ -8,7 +9,9 @@ type GameState interface {
 	OnStart()
 }
 
-type GameStateEnum int
+// GameStateEnum is declared with the contracts, so the app context can switch
+// states without importing this package.
+type GameStateEnum = contractstate.GameStateEnum
 
 type StateMap map[GameStateEnum]GameState
 
@@ -23,3 +26,12 @@ func NewBaseState(stateMap map[GameStateEnum]GameState) *BaseState {
 }
 
 func (s *BaseState) OnStart() {}
+
+// SetAppContext gives the state access to the game systems.
+func (s *BaseState) SetAppContext(ctx *core.AppContext) {
+	s.ctx = ctx
+}
+
+func (s *BaseState) AppContext() *core.AppContext {
+	return s.ctx
+}
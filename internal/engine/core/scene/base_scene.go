@@ -9,6 +9,7 @@ import (
 	"github.com/leandroatallah/drummer/internal/engine/core"
 	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
 	"github.com/leandroatallah/drummer/internal/engine/systems/imagemanager"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
 	"github.com/leandroatallah/drummer/internal/engine/systems/physics"
 )
 
@@ -71,6 +72,10 @@ func (s *BaseScene) ImageManager() *imagemanager.ImageManager {
 	return s.imagemanager
 }
 
+func (s *BaseScene) Input() *input.Manager {
+	return s.AppContext.InputManager
+}
+
 func (s *BaseScene) EnableKeys() {
 	s.IsKeysDisabled = false
 }
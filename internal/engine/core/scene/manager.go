@@ -53,6 +53,11 @@ func (m *SceneManager) SwitchTo(scene navigation.Scene) {
 
 	m.current = scene
 
+	// Touch zones belong to the scene that set them.
+	if m.appContext != nil && m.appContext.InputManager != nil {
+		m.appContext.InputManager.SetZones(nil)
+	}
+
 	if m.current != nil {
 		m.current.OnStart()
 	}
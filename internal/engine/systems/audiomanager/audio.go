@@ -2,11 +2,13 @@ package audiomanager
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2/audio"
@@ -16,7 +18,7 @@ import (
 )
 
 const (
-	sampleRate = 44100
+	SampleRate = 44100
 )
 
 type AudioItem struct {
@@ -31,20 +33,53 @@ func (a *AudioItem) Data() []byte {
 	return a.data
 }
 
+// AudioManager keeps the sounds of the game, and mixes them on buses. Its
+// methods can be called from the fade goroutines.
 type AudioManager struct {
 	audioContext *audio.Context
+
+	mu           sync.Mutex
 	audioPlayers map[string]*audio.Player
-	volume       float64
+	// soundBus is the bus of each sound. soundFade holds the sounds fading on
+	// their own, under the volume of their bus.
+	soundBus  map[string]Bus
+	soundFade map[string]*soundFade
+	// streams are the players made by NewPlayer, with their bus, until they
+	// are closed.
+	streams map[*audio.Player]Bus
+	buses   map[Bus]*bus
+	ducking []duckRule
+}
+
+type soundFade struct {
+	level float64
 }
 
 func NewAudioManager() *AudioManager {
+	return newAudioManager(audio.NewContext(SampleRate))
+}
+
+func newAudioManager(context *audio.Context) *AudioManager {
 	return &AudioManager{
-		audioContext: audio.NewContext(sampleRate),
+		audioContext: context,
 		audioPlayers: make(map[string]*audio.Player),
-		volume:       1.0,
+		soundBus:     make(map[string]Bus),
+		soundFade:    make(map[string]*soundFade),
+		streams:      make(map[*audio.Player]Bus),
+		buses:        make(map[Bus]*bus),
 	}
 }
 
+// soundVolume is the volume of a sound of the manager. It must be called with
+// the lock held.
+func (am *AudioManager) soundVolume(name string) float64 {
+	volume := am.bus(am.soundBus[name]).volume()
+	if f, ok := am.soundFade[name]; ok {
+		volume *= f.level
+	}
+	return volume
+}
+
 func (am *AudioManager) Load(path string) (*AudioItem, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -85,31 +120,32 @@ func (am *AudioManager) LoadFromFS(fs fs.FS, path string) (*AudioItem, error) {
 	return &AudioItem{path, bs}, nil
 }
 
-func (am *AudioManager) Add(name string, data []byte) {
-	var s io.ReadSeeker
-	var err error
-
+// Decode decodes an MP3, OGG or WAV file into 16-bit little endian stereo
+// samples at the audio context sample rate. The format is picked by the file
+// extension of name.
+func Decode(name string, data []byte) (io.ReadSeeker, error) {
 	switch {
 	case strings.HasSuffix(name, ".mp3"):
-		s, err = mp3.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
-		if err != nil {
-			log.Printf("failed to decode mp3 file: %v", err)
-			return
-		}
+		return mp3.DecodeWithSampleRate(SampleRate, bytes.NewReader(data))
 	case strings.HasSuffix(name, ".ogg"):
-		s, err = vorbis.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
-		if err != nil {
-			log.Printf("failed to decode ogg file: %v", err)
-			return
-		}
+		return vorbis.DecodeWithSampleRate(SampleRate, bytes.NewReader(data))
 	case strings.HasSuffix(name, ".wav"):
-		s, err = wav.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
-		if err != nil {
-			log.Printf("failed to decode wav file: %v", err)
-			return
-		}
+		return wav.DecodeWithSampleRate(SampleRate, bytes.NewReader(data))
 	default:
-		log.Printf("unsupported audio format: %s", name)
+		return nil, fmt.Errorf("unsupported audio format: %s", name)
+	}
+}
+
+// Add keeps a sound on the music bus.
+func (am *AudioManager) Add(name string, data []byte) {
+	am.AddTo(BusMusic, name, data)
+}
+
+// AddTo keeps a sound on a bus.
+func (am *AudioManager) AddTo(b Bus, name string, data []byte) {
+	s, err := Decode(name, data)
+	if err != nil {
+		log.Printf("failed to decode audio file %s: %v", name, err)
 		return
 	}
 
@@ -118,21 +154,48 @@ func (am *AudioManager) Add(name string, data []byte) {
 		log.Printf("failed to create audio player: %v", err)
 		return
 	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
 	am.audioPlayers[name] = p
+	am.soundBus[name] = b
+	p.SetVolume(am.soundVolume(name))
 }
 
-func (am *AudioManager) PlayMusic(name string) *audio.Player {
+// SoundBus returns the bus of a sound.
+func (am *AudioManager) SoundBus(name string) Bus {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	return am.soundBus[name]
+}
+
+// player returns a sound ready to play at the volume of its bus, or nil when
+// it wasn't added. It must be called with the lock held.
+func (am *AudioManager) player(name string) *audio.Player {
 	player, ok := am.audioPlayers[name]
 	if !ok {
 		log.Printf("audio player not found: %s", name)
 		return nil
 	}
-	player.SetVolume(am.volume)
+	delete(am.soundFade, name)
+	player.SetVolume(am.soundVolume(name))
+	return player
+}
+
+func (am *AudioManager) PlayMusic(name string) *audio.Player {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	player := am.player(name)
+	if player == nil {
+		return nil
+	}
 	player.Play()
 	return player
 }
 
 func (am *AudioManager) PauseMusic(name string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
 	player, ok := am.audioPlayers[name]
 	if !ok {
 		log.Printf("audio player not found: %s", name)
@@ -142,113 +205,146 @@ func (am *AudioManager) PauseMusic(name string) {
 }
 
 func (am *AudioManager) PlaySound(name string) *audio.Player {
-	player, ok := am.audioPlayers[name]
-	if !ok {
-		log.Printf("audio player not found: %s", name)
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	player := am.player(name)
+	if player == nil {
 		return nil
 	}
-	player.SetVolume(am.volume)
 	player.Rewind()
 	player.Play()
 	return player
 }
 
-func (am *AudioManager) SetVolume(volume float64) {
-	am.volume = volume
-	for _, player := range am.audioPlayers {
-		player.SetVolume(am.volume)
+// PlayFrom plays a sound starting at the given position.
+func (am *AudioManager) PlayFrom(name string, position time.Duration) *audio.Player {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	player := am.player(name)
+	if player == nil {
+		return nil
+	}
+	if err := player.SetPosition(position); err != nil {
+		log.Printf("failed to seek %s: %v", name, err)
+	}
+	player.Play()
+	return player
+}
+
+// NewPlayer creates a player for a stream that is not a sound of the manager,
+// like a song played at another speed. It plays on the music bus and follows
+// its volume until it is closed with ClosePlayer.
+func (am *AudioManager) NewPlayer(stream io.ReadSeeker) (*audio.Player, error) {
+	player, err := am.audioContext.NewPlayer(stream)
+	if err != nil {
+		return nil, err
 	}
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.streams[player] = BusMusic
+	player.SetVolume(am.bus(BusMusic).volume())
+	return player, nil
 }
 
-func (am *AudioManager) Volume() float64 {
-	return am.volume
+// ClosePlayer closes a player made by NewPlayer, and takes it off its bus.
+func (am *AudioManager) ClosePlayer(player *audio.Player) error {
+	am.mu.Lock()
+	delete(am.streams, player)
+	am.mu.Unlock()
+	return player.Close()
 }
 
 func (am *AudioManager) PauseAll() {
+	am.mu.Lock()
+	defer am.mu.Unlock()
 	for _, player := range am.audioPlayers {
 		player.Pause()
 	}
-}
-
-func (am *AudioManager) FadeOutAll(duration time.Duration) {
-	initialVolume := am.volume
-	if initialVolume == 0 {
-		return
+	for player := range am.streams {
+		player.Pause()
 	}
-
-	go func() {
-		ticker := time.NewTicker(100 * time.Millisecond)
-		defer ticker.Stop()
-
-		startTime := time.Now()
-
-		for range ticker.C {
-			elapsed := time.Since(startTime)
-			if elapsed >= duration {
-				am.SetVolume(0)
-				am.PauseAll()
-				return
-			}
-
-			progress := float64(elapsed) / float64(duration)
-			newVolume := initialVolume * (1 - progress)
-			if newVolume < 0 {
-				newVolume = 0
-			}
-			am.SetVolume(newVolume)
-		}
-	}()
 }
 
+// FadeOut fades a sound out over duration, then pauses it. Playing it again
+// stops the fade.
 func (am *AudioManager) FadeOut(name string, duration time.Duration) {
-	player, ok := am.audioPlayers[name]
-	if !ok {
+	am.mu.Lock()
+	if _, ok := am.audioPlayers[name]; !ok {
+		am.mu.Unlock()
 		log.Printf("audio player not found: %s", name)
 		return
 	}
-
-	initialVolume := player.Volume()
-	if initialVolume == 0 {
-		return
+	fade := &soundFade{level: 1}
+	if f, ok := am.soundFade[name]; ok {
+		fade.level = f.level
 	}
+	am.soundFade[name] = fade
+	initialLevel := fade.level
+	am.mu.Unlock()
 
 	go func() {
-		ticker := time.NewTicker(100 * time.Millisecond)
+		ticker := time.NewTicker(fadeTick)
 		defer ticker.Stop()
 
 		startTime := time.Now()
 
 		for range ticker.C {
-			elapsed := time.Since(startTime)
-			if elapsed >= duration {
-				player.SetVolume(0)
-				player.Pause()
+			progress := 1.0
+			if duration > 0 {
+				progress = min(float64(time.Since(startTime))/float64(duration), 1)
+			}
+
+			am.mu.Lock()
+			if am.soundFade[name] != fade {
+				// The sound was played again, or another fade took over.
+				am.mu.Unlock()
 				return
 			}
+			fade.level = initialLevel * (1 - progress)
+			player := am.audioPlayers[name]
+			player.SetVolume(am.soundVolume(name))
+			if progress == 1 {
+				player.Pause()
+			}
+			am.mu.Unlock()
 
-			progress := float64(elapsed) / float64(duration)
-			newVolume := initialVolume * (1 - progress)
-			if newVolume < 0 {
-				newVolume = 0
+			if progress == 1 {
+				return
 			}
-			player.SetVolume(newVolume)
 		}
 	}()
 }
 
 func (am *AudioManager) IsPlayingSomething() bool {
+	am.mu.Lock()
+	defer am.mu.Unlock()
 	for _, player := range am.audioPlayers {
 		if player.IsPlaying() {
 			return true
 		}
 	}
+	for player := range am.streams {
+		if player.IsPlaying() {
+			return true
+		}
+	}
 	return false
 }
 
 func (am *AudioManager) IsPlaying(name string) bool {
+	am.mu.Lock()
+	defer am.mu.Unlock()
 	audio, ok := am.audioPlayers[name]
 	if !ok {
 		return false
 	}
 	return audio.IsPlaying()
 }
+
+// Has reports whether a sound with the given name was added.
+func (am *AudioManager) Has(name string) bool {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	_, ok := am.audioPlayers[name]
+	return ok
+}
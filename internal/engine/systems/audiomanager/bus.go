@@ -0,0 +1,247 @@
+package audiomanager
+
+import "time"
+
+// Bus is a channel of the mixer. Every sound plays on a bus, and follows its
+// volume.
+type Bus string
+
+const (
+	BusMusic Bus = "music"
+	BusSFX   Bus = "sfx"
+	BusUI    Bus = "ui"
+)
+
+const (
+	// fadeTick is how often a fade changes the volume.
+	fadeTick = 50 * time.Millisecond
+	// duckStep is how much a ducked bus moves toward its level every Update,
+	// so it doesn't jump.
+	duckStep = 0.1
+)
+
+// bus holds the levels of a bus. Its volume is gain × fade × duck, or
+// nothing when muted.
+type bus struct {
+	gain  float64
+	fade  float64
+	duck  float64
+	muted bool
+	// fades counts the fades started, so a fade stops when another starts.
+	fades int
+}
+
+func newBus() *bus {
+	return &bus{gain: 1, fade: 1, duck: 1}
+}
+
+func (b *bus) volume() float64 {
+	if b.muted {
+		return 0
+	}
+	return b.gain * b.fade * b.duck
+}
+
+// duckRule lowers the target bus to level while a sound of the trigger bus
+// plays.
+type duckRule struct {
+	trigger Bus
+	target  Bus
+	level   float64
+}
+
+// bus returns the levels of a bus, creating them the first time. It must be
+// called with the lock held.
+func (am *AudioManager) bus(name Bus) *bus {
+	b, ok := am.buses[name]
+	if !ok {
+		b = newBus()
+		am.buses[name] = b
+	}
+	return b
+}
+
+// updateBus sets the volume of the players of a bus. It must be called with
+// the lock held.
+func (am *AudioManager) updateBus(name Bus) {
+	for sound, player := range am.audioPlayers {
+		if am.soundBus[sound] == name {
+			player.SetVolume(am.soundVolume(sound))
+		}
+	}
+	for player, b := range am.streams {
+		if b == name {
+			player.SetVolume(am.bus(name).volume())
+		}
+	}
+}
+
+// SetBusGain sets the volume of a bus, from 0 to 1.
+func (am *AudioManager) SetBusGain(name Bus, gain float64) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.bus(name).gain = gain
+	am.updateBus(name)
+}
+
+func (am *AudioManager) BusGain(name Bus) float64 {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	return am.bus(name).gain
+}
+
+// MuteBus silences a bus, or gives it its volume back, keeping its gain.
+func (am *AudioManager) MuteBus(name Bus, muted bool) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.bus(name).muted = muted
+	am.updateBus(name)
+}
+
+func (am *AudioManager) IsBusMuted(name Bus) bool {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	return am.bus(name).muted
+}
+
+// SetBusFade puts the fade level of a bus at once, stopping its fade.
+func (am *AudioManager) SetBusFade(name Bus, level float64) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	b := am.bus(name)
+	b.fades++
+	b.fade = level
+	am.updateBus(name)
+}
+
+// FadeBus moves the fade level of a bus to level over duration.
+func (am *AudioManager) FadeBus(name Bus, level float64, duration time.Duration) {
+	am.fadeBus(name, level, duration, false)
+}
+
+// FadeOutBus fades a bus out over duration, then pauses its sounds.
+func (am *AudioManager) FadeOutBus(name Bus, duration time.Duration) {
+	am.fadeBus(name, 0, duration, true)
+}
+
+func (am *AudioManager) fadeBus(name Bus, level float64, duration time.Duration, pause bool) {
+	am.mu.Lock()
+	b := am.bus(name)
+	b.fades++
+	id, from := b.fades, b.fade
+	am.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(fadeTick)
+		defer ticker.Stop()
+
+		startTime := time.Now()
+
+		for range ticker.C {
+			progress := 1.0
+			if duration > 0 {
+				progress = min(float64(time.Since(startTime))/float64(duration), 1)
+			}
+
+			am.mu.Lock()
+			if b.fades != id {
+				// Another fade took over.
+				am.mu.Unlock()
+				return
+			}
+			b.fade = from + (level-from)*progress
+			am.updateBus(name)
+			if progress == 1 && pause {
+				am.pauseBus(name)
+			}
+			am.mu.Unlock()
+
+			if progress == 1 {
+				return
+			}
+		}
+	}()
+}
+
+// pauseBus pauses the sounds of a bus. It must be called with the lock held.
+func (am *AudioManager) pauseBus(name Bus) {
+	for sound, player := range am.audioPlayers {
+		if am.soundBus[sound] == name {
+			player.Pause()
+		}
+	}
+	for player, b := range am.streams {
+		if b == name {
+			player.Pause()
+		}
+	}
+}
+
+// SetDucking lowers the target bus to level while a sound of the trigger bus
+// plays, like the music under the menu sounds. A level of 1 stops it.
+func (am *AudioManager) SetDucking(trigger, target Bus, level float64) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	for i, rule := range am.ducking {
+		if rule.trigger == trigger && rule.target == target {
+			am.ducking = append(am.ducking[:i], am.ducking[i+1:]...)
+			break
+		}
+	}
+	if level < 1 {
+		am.ducking = append(am.ducking, duckRule{trigger, target, level})
+	}
+}
+
+// Update moves the ducked buses toward their levels. It is called every tick.
+func (am *AudioManager) Update() {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	for name, b := range am.buses {
+		level := am.duckLevel(name)
+		if b.duck == level {
+			continue
+		}
+		if b.duck < level {
+			b.duck = min(b.duck+duckStep, level)
+		} else {
+			b.duck = max(b.duck-duckStep, level)
+		}
+		am.updateBus(name)
+	}
+}
+
+// duckLevel is the level the ducking rules give a bus now. It must be called
+// with the lock held.
+func (am *AudioManager) duckLevel(name Bus) float64 {
+	return duckLevel(am.ducking, name, am.busPlaying)
+}
+
+// duckLevel is the lowest level the rules give the target bus, from the
+// buses that are playing.
+func duckLevel(rules []duckRule, target Bus, playing func(Bus) bool) float64 {
+	level := 1.0
+	for _, rule := range rules {
+		if rule.target == target && playing(rule.trigger) {
+			level = min(level, rule.level)
+		}
+	}
+	return level
+}
+
+// busPlaying reports whether a sound of a bus is playing. It must be called
+// with the lock held.
+func (am *AudioManager) busPlaying(name Bus) bool {
+	for sound, player := range am.audioPlayers {
+		if am.soundBus[sound] == name && player.IsPlaying() {
+			return true
+		}
+	}
+	for player, b := range am.streams {
+		if b == name && player.IsPlaying() {
+			return true
+		}
+	}
+	return false
+}
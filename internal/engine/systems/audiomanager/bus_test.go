@@ -0,0 +1,121 @@
+package audiomanager
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBusVolume(t *testing.T) {
+	b := newBus()
+	if got := b.volume(); got != 1 {
+		t.Errorf("new bus volume = %v, want 1", got)
+	}
+
+	b.gain, b.fade, b.duck = 0.5, 0.5, 0.4
+	if got := b.volume(); math.Abs(got-0.1) > 1e-9 {
+		t.Errorf("volume = %v, want gain × fade × duck = 0.1", got)
+	}
+
+	b.muted = true
+	if got := b.volume(); got != 0 {
+		t.Errorf("muted volume = %v, want 0", got)
+	}
+	b.muted = false
+	if got := b.volume(); math.Abs(got-0.1) > 1e-9 {
+		t.Errorf("unmuted volume = %v, want the levels kept", got)
+	}
+}
+
+func TestSetDucking(t *testing.T) {
+	am := newAudioManager(nil)
+
+	am.SetDucking(BusUI, BusMusic, 0.4)
+	am.SetDucking(BusSFX, BusMusic, 0.6)
+	am.SetDucking(BusUI, BusMusic, 0.3)
+	if len(am.ducking) != 2 {
+		t.Fatalf("rules = %+v, want the UI rule replaced", am.ducking)
+	}
+
+	am.SetDucking(BusSFX, BusMusic, 1)
+	if len(am.ducking) != 1 || am.ducking[0] != (duckRule{BusUI, BusMusic, 0.3}) {
+		t.Errorf("rules = %+v, want the SFX rule removed", am.ducking)
+	}
+}
+
+func TestDuckLevel(t *testing.T) {
+	rules := []duckRule{
+		{BusUI, BusMusic, 0.4},
+		{BusSFX, BusMusic, 0.6},
+	}
+	playing := func(buses ...Bus) func(Bus) bool {
+		return func(name Bus) bool {
+			for _, b := range buses {
+				if b == name {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []struct {
+		playing []Bus
+		target  Bus
+		want    float64
+	}{
+		{nil, BusMusic, 1},
+		{[]Bus{BusSFX}, BusMusic, 0.6},
+		{[]Bus{BusUI, BusSFX}, BusMusic, 0.4},
+		{[]Bus{BusMusic}, BusMusic, 1},
+		{[]Bus{BusUI}, BusSFX, 1},
+	}
+	for _, tt := range tests {
+		if got := duckLevel(rules, tt.target, playing(tt.playing...)); got != tt.want {
+			t.Errorf("duckLevel(%s) with %v playing = %v, want %v", tt.target, tt.playing, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateMovesDuckBack(t *testing.T) {
+	am := newAudioManager(nil)
+	am.SetDucking(BusUI, BusMusic, 0.4)
+	am.bus(BusMusic).duck = 0.4
+
+	// Nothing plays on the UI bus, so the music comes back a step at a time.
+	am.Update()
+	if got := am.bus(BusMusic).duck; math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("duck = %v, want one step up to 0.5", got)
+	}
+	for range 10 {
+		am.Update()
+	}
+	if got := am.bus(BusMusic).duck; got != 1 {
+		t.Errorf("duck = %v, want 1", got)
+	}
+}
+
+func TestFadeCancel(t *testing.T) {
+	am := newAudioManager(nil)
+
+	am.FadeBus(BusMusic, 0, 200*time.Millisecond)
+	am.SetBusFade(BusMusic, 1)
+	time.Sleep(4 * fadeTick)
+	am.mu.Lock()
+	fade := am.bus(BusMusic).fade
+	am.mu.Unlock()
+	if fade != 1 {
+		t.Errorf("fade = %v, want the fade stopped by SetBusFade", fade)
+	}
+
+	// A new fade takes over from the one running.
+	am.FadeOutBus(BusMusic, time.Second)
+	am.FadeBus(BusMusic, 0.5, 0)
+	time.Sleep(4 * fadeTick)
+	am.mu.Lock()
+	fade = am.bus(BusMusic).fade
+	am.mu.Unlock()
+	if fade != 0.5 {
+		t.Errorf("fade = %v, want 0.5 from the last fade", fade)
+	}
+}
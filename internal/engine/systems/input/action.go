@@ -0,0 +1,132 @@
+package input
+
+// Action is something the player does, whatever input triggers it.
+type Action int
+
+const (
+	ActionLane1 Action = iota
+	ActionLane2
+	ActionLane3
+	ActionLane4
+	ActionLane5
+	ActionLane6
+	ActionLane7
+	ActionBonus
+	ActionPause
+	ActionConfirm
+	ActionBack
+	ActionUp
+	ActionDown
+	ActionLeft
+	ActionRight
+	ActionPractice
+	ActionReplay
+	ActionFailMode
+	ActionEditor
+	ActionRetry
+	ActionLoopStart
+	ActionLoopEnd
+	ActionLoopClear
+	ActionSlower
+	ActionFaster
+	ActionStretch
+	ActionPlayback
+	ActionSave
+	ActionRecord
+	ActionStepBack
+	ActionStepForward
+
+	actionCount
+)
+
+// Actions lists every action, in the order they are shown for rebinding.
+var Actions = []Action{
+	ActionLane1, ActionLane2, ActionLane3, ActionLane4, ActionLane5, ActionLane6, ActionLane7,
+	ActionBonus, ActionPause,
+	ActionConfirm, ActionBack, ActionUp, ActionDown, ActionLeft, ActionRight,
+	ActionPractice, ActionReplay, ActionFailMode, ActionEditor, ActionRetry,
+	ActionLoopStart, ActionLoopEnd, ActionLoopClear, ActionSlower, ActionFaster, ActionStretch,
+	ActionPlayback, ActionSave, ActionRecord, ActionStepBack, ActionStepForward,
+}
+
+var actionNames = map[Action]string{
+	ActionLane1:   "lane1",
+	ActionLane2:   "lane2",
+	ActionLane3:   "lane3",
+	ActionLane4:   "lane4",
+	ActionLane5:   "lane5",
+	ActionLane6:   "lane6",
+	ActionLane7:   "lane7",
+	ActionBonus:   "bonus",
+	ActionPause:   "pause",
+	ActionConfirm: "confirm",
+	ActionBack:    "back",
+	ActionUp:      "up",
+	ActionDown:    "down",
+	ActionLeft:    "left",
+	ActionRight:   "right",
+
+	ActionPractice: "practice",
+	ActionReplay:   "replay",
+	ActionFailMode: "fail",
+	ActionEditor:   "editor",
+	ActionRetry:    "retry",
+
+	ActionLoopStart:   "loopin",
+	ActionLoopEnd:     "loopout",
+	ActionLoopClear:   "noloop",
+	ActionSlower:      "slower",
+	ActionFaster:      "faster",
+	ActionStretch:     "stretch",
+	ActionPlayback:    "play",
+	ActionSave:        "save",
+	ActionRecord:      "record",
+	ActionStepBack:    "prev",
+	ActionStepForward: "next",
+}
+
+// LaneActions are the actions that play the lanes of the track, from left to
+// right.
+var LaneActions = []Action{
+	ActionLane1, ActionLane2, ActionLane3, ActionLane4, ActionLane5, ActionLane6, ActionLane7,
+}
+
+// String is the name of the action in saved bindings.
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+// ParseAction returns the action with the given name.
+func ParseAction(name string) (Action, bool) {
+	for a, n := range actionNames {
+		if n == name {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+// Kind is a group of actions read together. Actions of different kinds may
+// share inputs, but an input is bound to a single action of each kind.
+type Kind int
+
+const (
+	// KindPlay are the actions used while playing a song.
+	KindPlay Kind = iota
+	// KindMenu are the actions of the menus and lists.
+	KindMenu
+	// KindTool are the controls of practice mode and the chart editor, read
+	// along with the lanes.
+	KindTool
+)
+
+func (a Action) Kind() Kind {
+	switch {
+	case a <= ActionPause:
+		return KindPlay
+	case a <= ActionRetry:
+		return KindMenu
+	default:
+		return KindTool
+	}
+}
@@ -0,0 +1,321 @@
+package input
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// axisThreshold is how far a stick must be pushed to count as a press.
+const axisThreshold = 0.5
+
+// Device is the kind of input of a binding.
+type Device int
+
+const (
+	DeviceKeyboard Device = iota
+	DeviceGamepadButton
+	DeviceGamepadAxis
+)
+
+// Binding is a physical input that triggers an action. Gamepad bindings use
+// the standard layout and work on any connected gamepad.
+type Binding struct {
+	Device Device
+	// Code is an ebiten.Key, ebiten.StandardGamepadButton or
+	// ebiten.StandardGamepadAxis, depending on the device.
+	Code int
+	// Direction is the side, -1 or 1, an axis is pushed to.
+	Direction int
+}
+
+func Key(key ebiten.Key) Binding {
+	return Binding{Device: DeviceKeyboard, Code: int(key)}
+}
+
+func GamepadButton(button ebiten.StandardGamepadButton) Binding {
+	return Binding{Device: DeviceGamepadButton, Code: int(button)}
+}
+
+func GamepadAxis(axis ebiten.StandardGamepadAxis, direction int) Binding {
+	return Binding{Device: DeviceGamepadAxis, Code: int(axis), Direction: direction}
+}
+
+// IsGamepad reports whether the binding is a gamepad button or axis.
+func (b Binding) IsGamepad() bool {
+	return b.Device != DeviceKeyboard
+}
+
+var buttonNames = map[ebiten.StandardGamepadButton]string{
+	ebiten.StandardGamepadButtonRightBottom:      "A",
+	ebiten.StandardGamepadButtonRightRight:       "B",
+	ebiten.StandardGamepadButtonRightLeft:        "X",
+	ebiten.StandardGamepadButtonRightTop:         "Y",
+	ebiten.StandardGamepadButtonFrontTopLeft:     "L1",
+	ebiten.StandardGamepadButtonFrontTopRight:    "R1",
+	ebiten.StandardGamepadButtonFrontBottomLeft:  "L2",
+	ebiten.StandardGamepadButtonFrontBottomRight: "R2",
+	ebiten.StandardGamepadButtonCenterLeft:       "SELECT",
+	ebiten.StandardGamepadButtonCenterRight:      "START",
+	ebiten.StandardGamepadButtonLeftStick:        "L3",
+	ebiten.StandardGamepadButtonRightStick:       "R3",
+	ebiten.StandardGamepadButtonLeftTop:          "UP",
+	ebiten.StandardGamepadButtonLeftBottom:       "DOWN",
+	ebiten.StandardGamepadButtonLeftLeft:         "LEFT",
+	ebiten.StandardGamepadButtonLeftRight:        "RIGHT",
+	ebiten.StandardGamepadButtonCenterCenter:     "HOME",
+}
+
+var axisNames = map[ebiten.StandardGamepadAxis]string{
+	ebiten.StandardGamepadAxisLeftStickHorizontal:  "LX",
+	ebiten.StandardGamepadAxisLeftStickVertical:    "LY",
+	ebiten.StandardGamepadAxisRightStickHorizontal: "RX",
+	ebiten.StandardGamepadAxisRightStickVertical:   "RY",
+}
+
+// String is the binding as it is saved, like "key:ArrowLeft", "pad:A" or
+// "axis:LX-".
+func (b Binding) String() string {
+	switch b.Device {
+	case DeviceGamepadButton:
+		return "pad:" + buttonNames[ebiten.StandardGamepadButton(b.Code)]
+	case DeviceGamepadAxis:
+		sign := "+"
+		if b.Direction < 0 {
+			sign = "-"
+		}
+		return "axis:" + axisNames[ebiten.StandardGamepadAxis(b.Code)] + sign
+	default:
+		return "key:" + ebiten.Key(b.Code).String()
+	}
+}
+
+// keyLabels are the labels of the keys whose names are too long for menus.
+var keyLabels = map[ebiten.Key]string{
+	ebiten.KeyEscape:       "ESC",
+	ebiten.KeyMinus:        "-",
+	ebiten.KeyEqual:        "=",
+	ebiten.KeyBracketLeft:  "[",
+	ebiten.KeyBracketRight: "]",
+	ebiten.KeySemicolon:    ";",
+	ebiten.KeyQuote:        "'",
+}
+
+// Label is a short name of the binding for menus, without its device.
+func (b Binding) Label() string {
+	switch b.Device {
+	case DeviceGamepadButton, DeviceGamepadAxis:
+		_, name, _ := strings.Cut(b.String(), ":")
+		return name
+	default:
+		if label, ok := keyLabels[ebiten.Key(b.Code)]; ok {
+			return label
+		}
+		return strings.ToUpper(strings.TrimPrefix(ebiten.Key(b.Code).String(), "Arrow"))
+	}
+}
+
+// ParseBinding reads a binding written by Binding.String.
+func ParseBinding(s string) (Binding, error) {
+	device, name, ok := strings.Cut(s, ":")
+	if !ok {
+		return Binding{}, fmt.Errorf("invalid binding %q", s)
+	}
+
+	switch device {
+	case "key":
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(name)); err != nil {
+			return Binding{}, err
+		}
+		return Key(key), nil
+	case "pad":
+		for button, n := range buttonNames {
+			if n == name {
+				return GamepadButton(button), nil
+			}
+		}
+	case "axis":
+		if len(name) > 1 {
+			direction := 1
+			if strings.HasSuffix(name, "-") {
+				direction = -1
+			}
+			for axis, n := range axisNames {
+				if n == name[:len(name)-1] {
+					return GamepadAxis(axis, direction), nil
+				}
+			}
+		}
+	}
+	return Binding{}, fmt.Errorf("invalid binding %q", s)
+}
+
+// Bindings maps each action to the inputs that trigger it.
+type Bindings map[Action][]Binding
+
+// requiredKeys are the actions that always keep a keyboard binding, so the
+// menus can't be locked by a rebind.
+var requiredKeys = []Action{ActionConfirm, ActionBack}
+
+// DefaultBindings play the first four lanes with the arrow keys, DFJK, and the
+// gamepad d-pad, face buttons or left stick. The lanes of wider tracks go on
+// with the keys right of K, the triggers and the right stick button. The
+// practice and editor tools are on the keyboard only.
+func DefaultBindings() Bindings {
+	return Bindings{
+		ActionLane1: {Key(ebiten.KeyLeft), Key(ebiten.KeyD), GamepadButton(ebiten.StandardGamepadButtonLeftLeft), GamepadButton(ebiten.StandardGamepadButtonRightLeft), GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, -1)},
+		ActionLane2: {Key(ebiten.KeyDown), Key(ebiten.KeyF), GamepadButton(ebiten.StandardGamepadButtonLeftBottom), GamepadButton(ebiten.StandardGamepadButtonRightBottom), GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, 1)},
+		ActionLane3: {Key(ebiten.KeyUp), Key(ebiten.KeyJ), GamepadButton(ebiten.StandardGamepadButtonLeftTop), GamepadButton(ebiten.StandardGamepadButtonRightTop), GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, -1)},
+		ActionLane4: {Key(ebiten.KeyRight), Key(ebiten.KeyK), GamepadButton(ebiten.StandardGamepadButtonLeftRight), GamepadButton(ebiten.StandardGamepadButtonRightRight), GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, 1)},
+		ActionLane5: {Key(ebiten.KeyL), GamepadButton(ebiten.StandardGamepadButtonFrontBottomRight)},
+		ActionLane6: {Key(ebiten.KeySemicolon), GamepadButton(ebiten.StandardGamepadButtonFrontBottomLeft)},
+		ActionLane7: {Key(ebiten.KeyQuote), GamepadButton(ebiten.StandardGamepadButtonRightStick)},
+		ActionBonus: {Key(ebiten.KeySpace), GamepadButton(ebiten.StandardGamepadButtonFrontTopLeft), GamepadButton(ebiten.StandardGamepadButtonFrontTopRight)},
+		ActionPause: {Key(ebiten.KeyEscape), GamepadButton(ebiten.StandardGamepadButtonCenterRight)},
+
+		ActionConfirm: {Key(ebiten.KeyEnter), GamepadButton(ebiten.StandardGamepadButtonRightBottom), GamepadButton(ebiten.StandardGamepadButtonCenterRight)},
+		ActionBack:    {Key(ebiten.KeyEscape), GamepadButton(ebiten.StandardGamepadButtonRightRight), GamepadButton(ebiten.StandardGamepadButtonCenterLeft)},
+		ActionUp:      {Key(ebiten.KeyUp), GamepadButton(ebiten.StandardGamepadButtonLeftTop), GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, -1)},
+		ActionDown:    {Key(ebiten.KeyDown), GamepadButton(ebiten.StandardGamepadButtonLeftBottom), GamepadAxis(ebiten.StandardGamepadAxisLeftStickVertical, 1)},
+		ActionLeft:    {Key(ebiten.KeyLeft), GamepadButton(ebiten.StandardGamepadButtonLeftLeft), GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, -1)},
+		ActionRight:   {Key(ebiten.KeyRight), GamepadButton(ebiten.StandardGamepadButtonLeftRight), GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, 1)},
+
+		ActionPractice: {Key(ebiten.KeyP), GamepadButton(ebiten.StandardGamepadButtonRightTop)},
+		ActionReplay:   {Key(ebiten.KeyW)},
+		ActionFailMode: {Key(ebiten.KeyF)},
+		ActionEditor:   {Key(ebiten.KeyE)},
+		ActionRetry:    {Key(ebiten.KeyR), GamepadButton(ebiten.StandardGamepadButtonRightLeft)},
+
+		ActionLoopStart:   {Key(ebiten.KeyZ)},
+		ActionLoopEnd:     {Key(ebiten.KeyX)},
+		ActionLoopClear:   {Key(ebiten.KeyC)},
+		ActionSlower:      {Key(ebiten.KeyMinus)},
+		ActionFaster:      {Key(ebiten.KeyEqual)},
+		ActionStretch:     {Key(ebiten.KeyM)},
+		ActionPlayback:    {Key(ebiten.KeySpace)},
+		ActionSave:        {Key(ebiten.KeyS)},
+		ActionRecord:      {Key(ebiten.KeyR)},
+		ActionStepBack:    {Key(ebiten.KeyBracketLeft)},
+		ActionStepForward: {Key(ebiten.KeyBracketRight)},
+	}
+}
+
+// Encode returns the bindings keyed by action name, as they are saved.
+func (b Bindings) Encode() map[string][]string {
+	encoded := make(map[string][]string, len(b))
+	for action, bindings := range b {
+		names := make([]string, len(bindings))
+		for i, binding := range bindings {
+			names[i] = binding.String()
+		}
+		encoded[action.String()] = names
+	}
+	return encoded
+}
+
+// DecodeBindings reads saved bindings over the defaults. Actions that were not
+// saved keep their default bindings; invalid entries are reported and skipped,
+// and Confirm or Back saved without a key get their default keys back.
+func DecodeBindings(encoded map[string][]string) (Bindings, error) {
+	bindings := DefaultBindings()
+
+	var errs []string
+	for name, names := range encoded {
+		action, ok := ParseAction(name)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown action %q", name))
+			continue
+		}
+
+		list := make([]Binding, 0, len(names))
+		for _, n := range names {
+			binding, err := ParseBinding(n)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			list = append(list, binding)
+		}
+		bindings[action] = list
+	}
+
+	defaults := DefaultBindings()
+	for _, action := range requiredKeys {
+		if !hasKey(bindings[action]) {
+			errs = append(errs, fmt.Sprintf("no key for %s", action))
+			bindings.assign(action, slices.DeleteFunc(defaults[action], Binding.IsGamepad))
+		}
+	}
+
+	if len(errs) > 0 {
+		return bindings, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return bindings, nil
+}
+
+// Rebind makes a binding the only one of its device kind, keyboard or gamepad,
+// for an action. It is removed from the other actions of the same kind. A
+// rebind that would leave Confirm or Back without a key is refused, and the
+// bindings are kept as they were.
+func (b Bindings) Rebind(action Action, binding Binding) error {
+	return b.Assign(action, []Binding{binding})
+}
+
+// Assign is Rebind for several bindings at once: they replace the bindings of
+// their device kinds for the action.
+func (b Bindings) Assign(action Action, bindings []Binding) error {
+	next := b.Clone()
+	next.assign(action, bindings)
+	for _, required := range requiredKeys {
+		if !hasKey(next[required]) {
+			return fmt.Errorf("%s needs a key", required)
+		}
+	}
+
+	for a, list := range next {
+		b[a] = list
+	}
+	return nil
+}
+
+func (b Bindings) assign(action Action, bindings []Binding) {
+	replaced := make(map[bool]bool)
+	assigned := make(map[Binding]bool)
+	for _, binding := range bindings {
+		replaced[binding.IsGamepad()] = true
+		assigned[binding] = true
+	}
+
+	for a, list := range b {
+		if a.Kind() != action.Kind() {
+			continue
+		}
+
+		kept := list[:0:0]
+		for _, existing := range list {
+			if (a == action && replaced[existing.IsGamepad()]) || assigned[existing] {
+				continue
+			}
+			kept = append(kept, existing)
+		}
+		b[a] = kept
+	}
+	b[action] = append(b[action], bindings...)
+}
+
+// hasKey reports whether a keyboard binding is in the list.
+func hasKey(bindings []Binding) bool {
+	return slices.ContainsFunc(bindings, func(b Binding) bool { return !b.IsGamepad() })
+}
+
+// Clone returns a copy of the bindings that can be changed on its own.
+func (b Bindings) Clone() Bindings {
+	clone := make(Bindings, len(b))
+	for action, list := range b {
+		clone[action] = append([]Binding(nil), list...)
+	}
+	return clone
+}
@@ -0,0 +1,181 @@
+package input
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestParseBinding(t *testing.T) {
+	valid := map[string]Binding{
+		"key:ArrowLeft":    Key(ebiten.KeyLeft),
+		"key:D":            Key(ebiten.KeyD),
+		"key:BracketRight": Key(ebiten.KeyBracketRight),
+		"pad:A":            GamepadButton(ebiten.StandardGamepadButtonRightBottom),
+		"pad:START":        GamepadButton(ebiten.StandardGamepadButtonCenterRight),
+		"axis:LX-":         GamepadAxis(ebiten.StandardGamepadAxisLeftStickHorizontal, -1),
+		"axis:RY+":         GamepadAxis(ebiten.StandardGamepadAxisRightStickVertical, 1),
+	}
+	for s, want := range valid {
+		got, err := ParseBinding(s)
+		if err != nil || got != want {
+			t.Errorf("ParseBinding(%q) = %+v, %v, want %+v", s, got, err, want)
+		}
+		if got.String() != s {
+			t.Errorf("ParseBinding(%q).String() = %q", s, got.String())
+		}
+	}
+
+	for _, s := range []string{"", "D", "key:", "key:Hyper", "pad:Z", "axis:LZ+", "axis:-", "mouse:Left"} {
+		if _, err := ParseBinding(s); err == nil {
+			t.Errorf("ParseBinding(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestDefaultBindingsAreUniquePerKind(t *testing.T) {
+	seen := make(map[Kind]map[Binding]Action)
+	for action, list := range DefaultBindings() {
+		kind := action.Kind()
+		if seen[kind] == nil {
+			seen[kind] = make(map[Binding]Action)
+		}
+		for _, b := range list {
+			if other, ok := seen[kind][b]; ok {
+				t.Errorf("%s is bound to %s and %s", b, other, action)
+			}
+			seen[kind][b] = action
+		}
+	}
+
+	for _, action := range Actions {
+		if action.String() == "" {
+			t.Errorf("action %d has no name", action)
+		}
+		if parsed, ok := ParseAction(action.String()); !ok || parsed != action {
+			t.Errorf("ParseAction(%q) = %v, %v", action.String(), parsed, ok)
+		}
+	}
+}
+
+func TestDecodeBindings(t *testing.T) {
+	bindings := DefaultBindings()
+	if err := bindings.Rebind(ActionLane1, Key(ebiten.KeyA)); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeBindings(bindings.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, bindings) {
+		t.Errorf("decoded %v, want %v", decoded, bindings)
+	}
+
+	// Actions that were not saved keep their defaults.
+	decoded, err = DecodeBindings(map[string][]string{"lane2": {"key:S"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded[ActionLane2]; len(got) != 1 || got[0] != Key(ebiten.KeyS) {
+		t.Errorf("lane2 = %v, want [key:S]", got)
+	}
+	if !reflect.DeepEqual(decoded[ActionLane1], DefaultBindings()[ActionLane1]) {
+		t.Errorf("lane1 = %v, want the defaults", decoded[ActionLane1])
+	}
+
+	decoded, err = DecodeBindings(map[string][]string{
+		"lane1": {"key:A", "key:Hyper"},
+		"jump":  {"key:Space"},
+	})
+	if err == nil {
+		t.Error("invalid entries were not reported")
+	}
+	if got := decoded[ActionLane1]; len(got) != 1 || got[0] != Key(ebiten.KeyA) {
+		t.Errorf("lane1 = %v, want the valid entries kept", got)
+	}
+}
+
+func TestDecodeBindingsRestoresRequiredKeys(t *testing.T) {
+	decoded, err := DecodeBindings(map[string][]string{
+		"confirm": {"pad:A"},
+		"up":      {"key:Enter"},
+	})
+	if err == nil {
+		t.Error("a confirm action without a key was not reported")
+	}
+	if !hasKey(decoded[ActionConfirm]) {
+		t.Errorf("confirm = %v, want its default key back", decoded[ActionConfirm])
+	}
+	if hasKey(decoded[ActionUp]) {
+		t.Errorf("up = %v, want Enter taken back by confirm", decoded[ActionUp])
+	}
+}
+
+func TestRebind(t *testing.T) {
+	bindings := DefaultBindings()
+
+	// D plays lane 1 by default: it moves to lane 2, and replaces the keys of
+	// lane 2 only.
+	if err := bindings.Rebind(ActionLane2, Key(ebiten.KeyD)); err != nil {
+		t.Fatal(err)
+	}
+	if got := bindings[ActionLane2]; got[len(got)-1] != Key(ebiten.KeyD) || countKeys(got) != 1 {
+		t.Errorf("lane2 = %v, want D as its only key", got)
+	}
+	if countKeys(bindings[ActionLane1]) != 1 {
+		t.Errorf("lane1 = %v, want D removed", bindings[ActionLane1])
+	}
+	if len(bindings[ActionLane2]) != len(DefaultBindings()[ActionLane2])-1 {
+		t.Errorf("lane2 = %v, want its gamepad bindings kept", bindings[ActionLane2])
+	}
+
+	// The menus have their own bindings: Up keeps the up arrow.
+	if err := bindings.Rebind(ActionLane3, Key(ebiten.KeyDown)); err != nil {
+		t.Fatal(err)
+	}
+	if bindings[ActionUp][0] != Key(ebiten.KeyUp) || bindings[ActionDown][0] != Key(ebiten.KeyDown) {
+		t.Errorf("a lane rebind changed the menu bindings: up %v, down %v", bindings[ActionUp], bindings[ActionDown])
+	}
+}
+
+func TestRebindKeepsRequiredKeys(t *testing.T) {
+	bindings := DefaultBindings()
+	before := bindings.Clone()
+
+	// Enter is the only key of Confirm.
+	if err := bindings.Rebind(ActionUp, Key(ebiten.KeyEnter)); err == nil {
+		t.Error("took the last key of confirm")
+	}
+	if err := bindings.Rebind(ActionBack, Key(ebiten.KeyEnter)); err == nil {
+		t.Error("moved the last key of confirm to back")
+	}
+	if !reflect.DeepEqual(bindings, before) {
+		t.Errorf("a refused rebind changed the bindings: %v", bindings)
+	}
+
+	// Confirm may change keys, and play actions may use Enter.
+	if err := bindings.Rebind(ActionConfirm, Key(ebiten.KeySpace)); err != nil {
+		t.Error(err)
+	}
+	if err := bindings.Rebind(ActionPause, Key(ebiten.KeyEnter)); err != nil {
+		t.Error(err)
+	}
+	if err := bindings.Rebind(ActionConfirm, GamepadButton(ebiten.StandardGamepadButtonRightTop)); err != nil {
+		t.Error(err)
+	}
+	if !hasKey(bindings[ActionConfirm]) {
+		t.Errorf("confirm = %v, lost its key to a gamepad rebind", bindings[ActionConfirm])
+	}
+}
+
+func countKeys(bindings []Binding) int {
+	n := 0
+	for _, b := range bindings {
+		if !b.IsGamepad() {
+			n++
+		}
+	}
+	return n
+}
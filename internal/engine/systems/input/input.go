@@ -1,6 +1,11 @@
 package input
 
-import "github.com/hajimehoshi/ebiten/v2"
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
 
 func IsSomeKeyPressed(keys ...ebiten.Key) bool {
 	for _, k := range keys {
@@ -11,14 +16,171 @@ func IsSomeKeyPressed(keys ...ebiten.Key) bool {
 	return false
 }
 
+// Zone is an area of the screen that triggers an action while it is touched or
+// clicked.
+type Zone struct {
+	Rect   image.Rectangle
+	Action Action
+}
+
+// Manager maps keyboard, gamepad and touch input to actions. It reads the
+// input once per tick, in Update, so every scene sees the same state. The mouse
+// works as one more touch, so touch zones can be played on desktop too.
 type Manager struct {
-	// TODO: Implement this
+	bindings Bindings
+	zones    []Zone
+
+	down     [actionCount]bool
+	prevDown [actionCount]bool
+
+	gamepads []ebiten.GamepadID
+	touches  []ebiten.TouchID
+	// pointers are the positions of the touches and of the mouse cursor while
+	// its left button is held.
+	pointers []image.Point
+	// axes holds the gamepad axis bindings pushed past the threshold, to tell
+	// when one was just pushed.
+	axes     map[Binding]bool
+	prevAxes map[Binding]bool
 }
 
 func NewManager() *Manager {
-	return &Manager{}
+	return &Manager{
+		bindings: DefaultBindings(),
+		axes:     make(map[Binding]bool),
+		prevAxes: make(map[Binding]bool),
+	}
 }
 
 func (m *Manager) Update() {
-	// TODO: Implement this
+	m.gamepads = ebiten.AppendGamepadIDs(m.gamepads[:0])
+	m.touches = ebiten.AppendTouchIDs(m.touches[:0])
+	m.pointers = m.pointers[:0]
+	for _, id := range m.touches {
+		m.pointers = append(m.pointers, image.Pt(ebiten.TouchPosition(id)))
+	}
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		m.pointers = append(m.pointers, image.Pt(ebiten.CursorPosition()))
+	}
+
+	m.prevAxes, m.axes = m.axes, m.prevAxes
+	clear(m.axes)
+	for _, id := range m.gamepads {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for axis := range axisNames {
+			value := ebiten.StandardGamepadAxisValue(id, axis)
+			switch {
+			case value <= -axisThreshold:
+				m.axes[GamepadAxis(axis, -1)] = true
+			case value >= axisThreshold:
+				m.axes[GamepadAxis(axis, 1)] = true
+			}
+		}
+	}
+
+	m.prevDown = m.down
+	for _, action := range Actions {
+		m.down[action] = m.isDown(action)
+	}
+}
+
+func (m *Manager) isDown(action Action) bool {
+	for _, b := range m.bindings[action] {
+		if m.isBindingDown(b) {
+			return true
+		}
+	}
+
+	for _, z := range m.zones {
+		if z.Action != action {
+			continue
+		}
+		for _, p := range m.pointers {
+			if p.In(z.Rect) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *Manager) isBindingDown(b Binding) bool {
+	switch b.Device {
+	case DeviceKeyboard:
+		return ebiten.IsKeyPressed(ebiten.Key(b.Code))
+	case DeviceGamepadButton:
+		for _, id := range m.gamepads {
+			if ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButton(b.Code)) {
+				return true
+			}
+		}
+	case DeviceGamepadAxis:
+		return m.axes[b]
+	}
+	return false
+}
+
+// IsPressed reports whether an action is held down.
+func (m *Manager) IsPressed(action Action) bool {
+	return m.down[action]
+}
+
+// IsJustPressed reports whether an action started this tick.
+func (m *Manager) IsJustPressed(action Action) bool {
+	return m.down[action] && !m.prevDown[action]
+}
+
+// IsJustReleased reports whether an action ended this tick.
+func (m *Manager) IsJustReleased(action Action) bool {
+	return !m.down[action] && m.prevDown[action]
+}
+
+func (m *Manager) Bindings() Bindings {
+	return m.bindings
+}
+
+func (m *Manager) SetBindings(bindings Bindings) {
+	m.bindings = bindings
+}
+
+// SetZones sets the touch areas of the current screen. Scenes set their own
+// zones when they start.
+func (m *Manager) SetZones(zones []Zone) {
+	m.zones = zones
+}
+
+// JustTapped returns where a touch or a mouse click started this tick, if any.
+func (m *Manager) JustTapped() (image.Point, bool) {
+	if ids := inpututil.AppendJustPressedTouchIDs(nil); len(ids) > 0 {
+		return image.Pt(ebiten.TouchPosition(ids[0])), true
+	}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return image.Pt(ebiten.CursorPosition()), true
+	}
+	return image.Point{}, false
+}
+
+// JustPressedBinding returns an input pressed this tick, if any, to bind it to
+// an action.
+func (m *Manager) JustPressedBinding() (Binding, bool) {
+	if keys := inpututil.AppendJustPressedKeys(nil); len(keys) > 0 {
+		return Key(keys[0]), true
+	}
+
+	for _, id := range m.gamepads {
+		if buttons := inpututil.AppendJustPressedStandardGamepadButtons(id, nil); len(buttons) > 0 {
+			if _, ok := buttonNames[buttons[0]]; ok {
+				return GamepadButton(buttons[0]), true
+			}
+		}
+	}
+
+	for b := range m.axes {
+		if !m.prevAxes[b] {
+			return b, true
+		}
+	}
+	return Binding{}, false
 }
@@ -0,0 +1,22 @@
+package savemanager
+
+import "io/fs"
+
+// MemoryStore keeps save files in memory, like the tests do. It has the Load
+// and Save methods of Manager.
+type MemoryStore map[string][]byte
+
+// Load returns the content of a save file. A missing file returns
+// fs.ErrNotExist.
+func (m MemoryStore) Load(name string) ([]byte, error) {
+	data, ok := m[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return data, nil
+}
+
+func (m MemoryStore) Save(name string, data []byte) error {
+	m[name] = data
+	return nil
+}
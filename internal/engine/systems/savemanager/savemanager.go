@@ -0,0 +1,32 @@
+package savemanager
+
+// Manager reads and writes small save files, such as high scores and settings.
+// Desktop builds keep them in the user config directory and the WASM build keeps
+// them in the browser localStorage.
+type Manager struct {
+	store store
+}
+
+// store is the platform specific storage backend.
+type store interface {
+	load(name string) ([]byte, error)
+	save(name string, data []byte) error
+}
+
+// NewSaveManager creates a save manager for an application. The name is used as
+// the directory, or key prefix, of every save file.
+func NewSaveManager(appName string) *Manager {
+	return &Manager{store: newStore(appName)}
+}
+
+// Load returns the content of a save file. A missing file returns an error
+// matching fs.ErrNotExist.
+func (m *Manager) Load(name string) ([]byte, error) {
+	return m.store.load(name)
+}
+
+// Save replaces the content of a save file. The write is atomic: a crash leaves
+// either the old or the new content, never a mix of both.
+func (m *Manager) Save(name string, data []byte) error {
+	return m.store.save(name, data)
+}
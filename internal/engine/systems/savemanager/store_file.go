@@ -0,0 +1,51 @@
+//go:build !js
+
+package savemanager
+
+import (
+	"os"
+	"path/filepath"
+)
+
+type fileStore struct {
+	dir string
+}
+
+func newStore(appName string) store {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return &fileStore{dir: filepath.Join(dir, appName)}
+}
+
+func (s *fileStore) load(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, name))
+}
+
+// save writes to a temporary file first and renames it over the old one.
+func (s *fileStore) save(name string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
@@ -0,0 +1,57 @@
+//go:build js
+
+package savemanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall/js"
+)
+
+type localStorageStore struct {
+	prefix string
+}
+
+func newStore(appName string) store {
+	return &localStorageStore{prefix: appName + "/"}
+}
+
+func (s *localStorageStore) storage() (js.Value, error) {
+	storage := js.Global().Get("localStorage")
+	if storage.IsUndefined() || storage.IsNull() {
+		return js.Value{}, errors.New("localStorage is not available")
+	}
+	return storage, nil
+}
+
+func (s *localStorageStore) load(name string) ([]byte, error) {
+	storage, err := s.storage()
+	if err != nil {
+		return nil, err
+	}
+
+	item := storage.Call("getItem", s.prefix+name)
+	if item.IsNull() {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return []byte(item.String()), nil
+}
+
+// save relies on setItem, which replaces the whole value at once.
+func (s *localStorageStore) save(name string, data []byte) (err error) {
+	storage, err := s.storage()
+	if err != nil {
+		return err
+	}
+
+	// setItem throws when the storage quota is exceeded.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to save %s: %v", name, r)
+		}
+	}()
+
+	storage.Call("setItem", s.prefix+name, string(data))
+	return nil
+}
@@ -0,0 +1,272 @@
+// Package timestretch plays decoded audio at a different speed.
+//
+// Sources are read from, and streams write, 16-bit little endian stereo PCM,
+// the format of the audio manager decoders.
+package timestretch
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	channels      = 2
+	bytesPerFrame = channels * 2
+
+	// frameSize is the length, in frames, of the grains overlapped by the
+	// time stretch. Grains overlap by half.
+	frameSize = 2048
+	hop       = frameSize / 2
+	// searchRadius is how far, in frames, a grain may move from its nominal
+	// position to line up with the previous one.
+	searchRadius = 512
+	// searchStep and searchDecimation trade alignment accuracy for speed.
+	searchStep       = 2
+	searchDecimation = 4
+
+	// resampleChunk is the number of frames rendered at once when resampling.
+	resampleChunk = 1024
+)
+
+// Mode is how a stream changes speed.
+type Mode int
+
+const (
+	// ModeStretch keeps the pitch of the audio.
+	ModeStretch Mode = iota
+	// ModeResample plays the audio slower and lower, like a tape. It is cheaper
+	// than ModeStretch.
+	ModeResample
+)
+
+func (m Mode) String() string {
+	if m == ModeResample {
+		return "resample"
+	}
+	return "stretch"
+}
+
+// Source is decoded audio that streams can share.
+type Source struct {
+	samples []float32
+	frames  int
+}
+
+func NewSource(pcm []byte) *Source {
+	frames := len(pcm) / bytesPerFrame
+	samples := make([]float32, frames*channels)
+	for i := range samples {
+		samples[i] = float32(int16(binary.LittleEndian.Uint16(pcm[i*2:]))) / 32768
+	}
+	return &Source{samples: samples, frames: frames}
+}
+
+// Stream plays a source at rate times its original speed. Positions and
+// lengths of the stream are in output time; multiply them by the rate to get
+// the time in the source audio.
+type Stream struct {
+	src    *Source
+	frames int
+	rate   float64
+	mode   Mode
+
+	// out is the output position in frames and pending holds rendered output
+	// that was not read yet.
+	out     int64
+	pending []byte
+
+	// source is the nominal source position of the next grain, in frames.
+	source float64
+	// prev is the source position of the last grain, or -1 after a seek.
+	prev   int
+	tail   []float64
+	window []float64
+}
+
+// NewStream returns a stream of src played at rate, which must be positive.
+func NewStream(src *Source, rate float64, mode Mode) *Stream {
+	window := make([]float64, frameSize)
+	for i := range window {
+		// A periodic Hann window sums to one when overlapped by half.
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/frameSize)
+	}
+
+	s := &Stream{
+		src:    src,
+		frames: src.frames,
+		rate:   rate,
+		mode:   mode,
+		tail:   make([]float64, hop*channels),
+		window: window,
+	}
+	s.reset()
+	return s
+}
+
+// Rate is the playback speed of the stream.
+func (s *Stream) Rate() float64 {
+	return s.rate
+}
+
+// Length is the size of the output in bytes.
+func (s *Stream) Length() int64 {
+	return int64(float64(s.frames)/s.rate) * bytesPerFrame
+}
+
+func (s *Stream) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 {
+		if s.source >= float64(s.frames) {
+			return 0, io.EOF
+		}
+		s.render()
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+func (s *Stream) Seek(offset int64, whence int) (int64, error) {
+	position := s.out*bytesPerFrame - int64(len(s.pending))
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position += offset
+	case io.SeekEnd:
+		position = s.Length() + offset
+	}
+	if position < 0 {
+		return 0, errors.New("timestretch: negative position")
+	}
+
+	s.out = position / bytesPerFrame
+	s.reset()
+	return position, nil
+}
+
+// reset starts rendering from the output position.
+func (s *Stream) reset() {
+	s.pending = nil
+	s.source = float64(s.out) * s.rate
+	s.prev = -1
+	clear(s.tail)
+}
+
+func (s *Stream) render() {
+	if s.rate == 1 {
+		s.renderCopy()
+		return
+	}
+	if s.mode == ModeResample {
+		s.renderResample()
+		return
+	}
+	s.renderStretch()
+}
+
+// renderCopy passes the source through.
+func (s *Stream) renderCopy() {
+	from := int(s.source)
+	to := min(from+resampleChunk, s.frames)
+
+	out := make([]float64, 0, (to-from)*channels)
+	for _, v := range s.src.samples[from*channels : to*channels] {
+		out = append(out, float64(v))
+	}
+	s.emit(out)
+	s.source = float64(to)
+}
+
+// renderResample reads the source at rate frames per output frame, with
+// linear interpolation.
+func (s *Stream) renderResample() {
+	out := make([]float64, 0, resampleChunk*channels)
+	for range resampleChunk {
+		if s.source >= float64(s.frames) {
+			break
+		}
+		i := int(s.source)
+		frac := s.source - float64(i)
+		for c := range channels {
+			a := s.sample(i, c)
+			b := s.sample(i+1, c)
+			out = append(out, a+(b-a)*frac)
+		}
+		s.source += s.rate
+	}
+	s.emit(out)
+}
+
+// renderStretch renders one hop of output with WSOLA: grains are taken every
+// rate * hop frames of the source and overlapped every hop frames. Each grain
+// is moved a little to line up with the previous one, which avoids the
+// phasing of a plain overlap-add.
+func (s *Stream) renderStretch() {
+	start := int(s.source)
+	if s.prev >= 0 {
+		start = s.align(start, s.prev+hop)
+	}
+
+	out := make([]float64, hop*channels)
+	for i := range frameSize {
+		w := s.window[i]
+		for c := range channels {
+			v := s.sample(start+i, c) * w
+			if i < hop {
+				out[i*channels+c] = s.tail[i*channels+c] + v
+			} else {
+				s.tail[(i-hop)*channels+c] = v
+			}
+		}
+	}
+
+	s.prev = start
+	s.source += s.rate * hop
+	s.emit(out)
+}
+
+// align returns the grain position near nominal whose start sounds most like
+// the source at natural, the continuation of the previous grain.
+func (s *Stream) align(nominal, natural int) int {
+	best, bestScore := nominal, math.Inf(-1)
+	for d := -searchRadius; d <= searchRadius; d += searchStep {
+		candidate := nominal + d
+		if candidate < 0 {
+			continue
+		}
+
+		score := 0.0
+		for i := 0; i < hop; i += searchDecimation {
+			score += s.mono(candidate+i) * s.mono(natural+i)
+		}
+		if score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
+
+func (s *Stream) sample(frame, channel int) float64 {
+	if frame < 0 || frame >= s.frames {
+		return 0
+	}
+	return float64(s.src.samples[frame*channels+channel])
+}
+
+func (s *Stream) mono(frame int) float64 {
+	return s.sample(frame, 0) + s.sample(frame, 1)
+}
+
+// emit converts rendered samples to PCM and queues them for reading.
+func (s *Stream) emit(out []float64) {
+	buf := make([]byte, len(out)*2)
+	for i, v := range out {
+		v = math.Max(-1, math.Min(1, v))
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(int16(v*32767)))
+	}
+	s.pending = buf
+	s.out += int64(len(out) / channels)
+}
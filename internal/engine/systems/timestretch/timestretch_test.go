@@ -0,0 +1,105 @@
+package timestretch
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+// sine returns frames of a 440 Hz stereo tone at 44.1 kHz, as PCM.
+func sine(frames int) []byte {
+	pcm := make([]byte, frames*bytesPerFrame)
+	for i := range frames {
+		v := int16(math.Sin(2*math.Pi*440*float64(i)/44100) * 16000)
+		binary.LittleEndian.PutUint16(pcm[i*bytesPerFrame:], uint16(v))
+		binary.LittleEndian.PutUint16(pcm[i*bytesPerFrame+2:], uint16(v))
+	}
+	return pcm
+}
+
+func readAll(t *testing.T, s *Stream) []byte {
+	t.Helper()
+	out, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestStreamLength(t *testing.T) {
+	const frames = 44100
+	src := NewSource(sine(frames))
+
+	for _, mode := range []Mode{ModeStretch, ModeResample} {
+		for _, rate := range []float64{0.5, 0.8, 1} {
+			s := NewStream(src, rate, mode)
+			want := int64(float64(frames)/rate) * bytesPerFrame
+			if s.Length() != want {
+				t.Errorf("%v at %v: Length() = %d, want %d", mode, rate, s.Length(), want)
+			}
+
+			// The output may run over by up to a grain.
+			got := int64(len(readAll(t, s)))
+			if got < want-frameSize*bytesPerFrame || got > want+frameSize*bytesPerFrame {
+				t.Errorf("%v at %v: read %d bytes, want about %d", mode, rate, got, want)
+			}
+		}
+	}
+}
+
+func TestStreamAtFullSpeedCopies(t *testing.T) {
+	pcm := sine(5000)
+	out := readAll(t, NewStream(NewSource(pcm), 1, ModeStretch))
+
+	if len(out) != len(pcm) {
+		t.Fatalf("read %d bytes, want %d", len(out), len(pcm))
+	}
+	for i := 0; i < len(pcm); i += 2 {
+		a := int16(binary.LittleEndian.Uint16(pcm[i:]))
+		b := int16(binary.LittleEndian.Uint16(out[i:]))
+		if d := int(a) - int(b); d < -1 || d > 1 {
+			t.Fatalf("sample %d = %d, want %d", i/2, b, a)
+		}
+	}
+}
+
+func TestStreamSeek(t *testing.T) {
+	s := NewStream(NewSource(sine(44100)), 0.5, ModeResample)
+
+	position, err := s.Seek(1000*bytesPerFrame, io.SeekStart)
+	if err != nil || position != 1000*bytesPerFrame {
+		t.Fatalf("Seek = %d, %v", position, err)
+	}
+	// The output position maps to rate times as far into the source.
+	if s.source != 500 {
+		t.Errorf("source position = %v, want 500", s.source)
+	}
+
+	position, err = s.Seek(-bytesPerFrame, io.SeekEnd)
+	if err != nil || position != s.Length()-bytesPerFrame {
+		t.Errorf("Seek from the end = %d, %v", position, err)
+	}
+	if _, err := s.Seek(-1, io.SeekStart); err == nil {
+		t.Error("seeked before the start")
+	}
+}
+
+func TestStreamStretchKeepsLevel(t *testing.T) {
+	src := NewSource(sine(44100))
+	out := readAll(t, NewStream(src, 0.5, ModeStretch))
+
+	// Grains overlapped by half with a Hann window sum to the original level,
+	// so the tone keeps its loudness once the first grain is in.
+	var sum float64
+	var n int
+	for i := frameSize * bytesPerFrame; i+2 <= len(out)-frameSize*bytesPerFrame; i += bytesPerFrame {
+		v := float64(int16(binary.LittleEndian.Uint16(out[i:]))) / 16000
+		sum += v * v
+		n++
+	}
+	rms := math.Sqrt(sum / float64(n))
+	if math.Abs(rms-math.Sqrt(0.5)) > 0.1 {
+		t.Errorf("RMS = %.3f, want about %.3f", rms, math.Sqrt(0.5))
+	}
+}
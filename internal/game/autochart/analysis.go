@@ -0,0 +1,310 @@
+package gameautochart
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	frameSize = 1024
+	// framesPerSecond is the resolution of the onset envelope.
+	framesPerSecond = 100
+
+	minBpm = 60
+	maxBpm = 200
+	// preferredBpm is the tempo favoured when a multiple of the beat period
+	// fits the onsets just as well.
+	preferredBpm = 120
+
+	// minOnsetGap is the shortest time between two detected onsets, in seconds.
+	minOnsetGap = 0.05
+	// thresholdWindow is the number of frames around an onset used to compute
+	// its adaptive threshold.
+	thresholdWindow = 10
+
+	// smoothSigma and smoothRadius shape the blur applied to the envelope
+	// before tempo estimation, in frames.
+	smoothSigma  = 1.5
+	smoothRadius = 4
+)
+
+// Band is a frequency band of a drum kit piece.
+type Band int
+
+const (
+	BandKick Band = iota
+	BandSnare
+	BandCymbal
+	BandHiHat
+	bandCount
+)
+
+func (b Band) String() string {
+	switch b {
+	case BandKick:
+		return "kick"
+	case BandSnare:
+		return "snare"
+	case BandCymbal:
+		return "cymbal"
+	case BandHiHat:
+		return "hihat"
+	}
+	return "unknown"
+}
+
+// bandRanges are the lower and upper frequencies of every band, in Hz.
+var bandRanges = [bandCount][2]float64{
+	BandKick:   {30, 150},
+	BandSnare:  {150, 2000},
+	BandCymbal: {2000, 6000},
+	BandHiHat:  {6000, 16000},
+}
+
+// Onset is a percussive hit found in the audio.
+type Onset struct {
+	// Time is the position of the hit in seconds.
+	Time float64
+	// Band is the frequency band with the strongest attack.
+	Band Band
+	// Strength is the onset envelope value, from 0 to 1.
+	Strength float64
+}
+
+// Analysis is the result of analysing a track.
+type Analysis struct {
+	Bpm float64
+	// Phase is the time of the first beat in seconds.
+	Phase    float64
+	Duration float64
+	Onsets   []Onset
+}
+
+// Options tune the analysis.
+type Options struct {
+	// Threshold is added to the local mean of the onset envelope to decide
+	// whether a peak is an onset. Lower values detect more onsets.
+	Threshold float64
+	// Bpm overrides the tempo estimation when greater than zero.
+	Bpm float64
+}
+
+func DefaultOptions() Options {
+	return Options{Threshold: 0.1}
+}
+
+// Analyze finds the tempo and the percussive onsets of mono samples in the
+// [-1, 1] range.
+func Analyze(samples []float64, sampleRate int, opts Options) *Analysis {
+	a := &Analysis{Duration: float64(len(samples)) / float64(sampleRate)}
+
+	flux := bandFlux(samples, sampleRate)
+	if len(flux) == 0 {
+		a.Bpm = opts.Bpm
+		return a
+	}
+	envelope := onsetEnvelope(flux)
+
+	// Frames are timed at the center of their window.
+	center := float64(frameSize) / 2 / float64(sampleRate)
+
+	a.Onsets = pickOnsets(envelope, flux, opts.Threshold, center)
+
+	period := float64(framesPerSecond) * 60 / estimateBpm(envelope)
+	if opts.Bpm > 0 {
+		period = float64(framesPerSecond) * 60 / opts.Bpm
+	}
+	a.Bpm = float64(framesPerSecond) * 60 / period
+	a.Phase = estimatePhase(envelope, period)/framesPerSecond + center
+
+	return a
+}
+
+// bandFlux returns the spectral flux of every band, frame by frame. The flux
+// is the sum of the increases in log magnitude of the band bins.
+func bandFlux(samples []float64, sampleRate int) [][bandCount]float64 {
+	hop := sampleRate / framesPerSecond
+	if len(samples) < frameSize || hop == 0 {
+		return nil
+	}
+
+	frames := (len(samples)-frameSize)/hop + 1
+	window := hann(frameSize)
+	buf := make([]complex128, frameSize)
+	prev := make([]float64, frameSize/2)
+	mag := make([]float64, frameSize/2)
+
+	binBand := make([]int, frameSize/2)
+	for k := range binBand {
+		binBand[k] = -1
+		freq := float64(k) * float64(sampleRate) / frameSize
+		for b, r := range bandRanges {
+			if freq >= r[0] && freq < r[1] {
+				binBand[k] = b
+			}
+		}
+	}
+
+	flux := make([][bandCount]float64, frames)
+	for f := range frames {
+		offset := f * hop
+		for i := range buf {
+			buf[i] = complex(samples[offset+i]*window[i], 0)
+		}
+		fft(buf)
+
+		for k := range mag {
+			mag[k] = math.Log1p(cmplx.Abs(buf[k]))
+			if b := binBand[k]; b >= 0 && f > 0 {
+				flux[f][b] += math.Max(0, mag[k]-prev[k])
+			}
+		}
+		prev, mag = mag, prev
+	}
+
+	// Scale every band to a peak of 1, so narrow bands weigh as much as wide
+	// ones.
+	for b := range bandCount {
+		peak := 0.0
+		for f := range flux {
+			peak = math.Max(peak, flux[f][b])
+		}
+		if peak == 0 {
+			continue
+		}
+		for f := range flux {
+			flux[f][b] /= peak
+		}
+	}
+
+	return flux
+}
+
+// onsetEnvelope sums the band fluxes into a single curve with a peak of 1.
+func onsetEnvelope(flux [][bandCount]float64) []float64 {
+	envelope := make([]float64, len(flux))
+	peak := 0.0
+	for f, bands := range flux {
+		for _, v := range bands {
+			envelope[f] += v
+		}
+		peak = math.Max(peak, envelope[f])
+	}
+	if peak > 0 {
+		for f := range envelope {
+			envelope[f] /= peak
+		}
+	}
+	return envelope
+}
+
+// pickOnsets returns the local maxima of the envelope that rise above its
+// local mean by threshold.
+func pickOnsets(envelope []float64, flux [][bandCount]float64, threshold, center float64) []Onset {
+	var onsets []Onset
+	minGap := int(minOnsetGap * framesPerSecond)
+	last := -minGap
+
+	for f := 1; f < len(envelope)-1; f++ {
+		v := envelope[f]
+		if v < envelope[f-1] || v <= envelope[f+1] || f-last < minGap {
+			continue
+		}
+
+		from, to := max(0, f-thresholdWindow), min(len(envelope), f+thresholdWindow+1)
+		mean := 0.0
+		for _, e := range envelope[from:to] {
+			mean += e
+		}
+		mean /= float64(to - from)
+		if v < mean+threshold {
+			continue
+		}
+
+		band := BandKick
+		for b := range bandCount {
+			if flux[f][b] > flux[f][band] {
+				band = b
+			}
+		}
+
+		onsets = append(onsets, Onset{
+			Time:     float64(f)/framesPerSecond + center,
+			Band:     band,
+			Strength: v,
+		})
+		last = f
+	}
+
+	return onsets
+}
+
+// estimateBpm finds the beat period with the strongest autocorrelation of the
+// onset envelope. Tempos far from preferredBpm are weighted down, so the beat
+// is not mistaken for half or double of it.
+func estimateBpm(envelope []float64) float64 {
+	mean := 0.0
+	for _, v := range envelope {
+		mean += v
+	}
+	mean /= float64(len(envelope))
+
+	// Onset peaks are a frame wide. Blur them, so periods that fall between
+	// frames still correlate.
+	centered := make([]float64, len(envelope))
+	for i := range envelope {
+		for j := -smoothRadius; j <= smoothRadius; j++ {
+			if k := i + j; k >= 0 && k < len(envelope) {
+				centered[i] += (envelope[k] - mean) * math.Exp(-float64(j*j)/(2*smoothSigma*smoothSigma))
+			}
+		}
+	}
+
+	minLag := framesPerSecond * 60 / maxBpm
+	maxLag := min(framesPerSecond*60/minBpm, len(centered)-1)
+	if maxLag <= minLag {
+		return preferredBpm
+	}
+
+	corr := make([]float64, maxLag+2)
+	for lag := minLag - 1; lag <= maxLag+1 && lag < len(centered); lag++ {
+		for i := 0; i+lag < len(centered); i++ {
+			corr[lag] += centered[i] * centered[i+lag]
+		}
+	}
+
+	best, bestScore := 0, math.Inf(-1)
+	for lag := minLag; lag <= maxLag; lag++ {
+		bpm := float64(framesPerSecond) * 60 / float64(lag)
+		octaves := math.Log2(bpm / preferredBpm)
+		score := corr[lag] * math.Exp(-octaves*octaves/2)
+		if score > bestScore {
+			best, bestScore = lag, score
+		}
+	}
+
+	// Refine the lag between frames with a parabola through its neighbours.
+	lag := float64(best)
+	y0, y1, y2 := corr[best-1], corr[best], corr[best+1]
+	if d := y0 - 2*y1 + y2; d < 0 {
+		lag += 0.5 * (y0 - y2) / d
+	}
+
+	return float64(framesPerSecond) * 60 / lag
+}
+
+// estimatePhase returns the frame of the first beat: the offset whose beat
+// grid collects the most onset energy.
+func estimatePhase(envelope []float64, period float64) float64 {
+	best, bestScore := 0, -1.0
+	for phase := 0; phase < int(math.Ceil(period)); phase++ {
+		score := 0.0
+		for t := float64(phase); int(math.Round(t)) < len(envelope); t += period {
+			score += envelope[int(math.Round(t))]
+		}
+		if score > bestScore {
+			best, bestScore = phase, score
+		}
+	}
+	return float64(best)
+}
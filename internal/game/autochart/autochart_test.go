@@ -0,0 +1,170 @@
+package gameautochart
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+const testSampleRate = 44100
+
+type click struct {
+	time float64
+	freq float64
+}
+
+// synthesize renders short decaying sine bursts.
+func synthesize(clicks []click, seconds float64) []float64 {
+	samples := make([]float64, int(seconds*testSampleRate))
+	for _, c := range clicks {
+		start := int(c.time * testSampleRate)
+		for i := 0; i < testSampleRate/10 && start+i < len(samples); i++ {
+			t := float64(i) / testSampleRate
+			attack := math.Min(1, t/0.001)
+			samples[start+i] += 0.5 * attack * math.Exp(-t/0.03) * math.Sin(2*math.Pi*c.freq*t)
+		}
+	}
+	return samples
+}
+
+// beatClicks places a click of freq on every beat of the given tempo, starting
+// at offset seconds.
+func beatClicks(bpm, offset, seconds, freq float64) []click {
+	var clicks []click
+	for t := offset; t < seconds-0.2; t += 60 / bpm {
+		clicks = append(clicks, click{t, freq})
+	}
+	return clicks
+}
+
+func TestFFT(t *testing.T) {
+	x := make([]complex128, 16)
+	for i := range x {
+		x[i] = complex(math.Cos(2*math.Pi*3*float64(i)/16), 0)
+	}
+	fft(x)
+
+	for k, v := range x {
+		want := 0.0
+		if k == 3 || k == 13 {
+			want = 8
+		}
+		if math.Abs(cmplx.Abs(v)-want) > 1e-9 {
+			t.Errorf("bin %d = %v, want magnitude %v", k, cmplx.Abs(v), want)
+		}
+	}
+}
+
+func TestAnalyzeBpm(t *testing.T) {
+	tests := []struct {
+		bpm    float64
+		offset float64
+	}{
+		{90, 0.3},
+		{120, 0.5},
+		{150, 0.25},
+		{165, 0.1},
+	}
+
+	for _, tt := range tests {
+		clicks := beatClicks(tt.bpm, tt.offset, 12, 80)
+		a := Analyze(synthesize(clicks, 12), testSampleRate, DefaultOptions())
+
+		if math.Abs(a.Bpm-tt.bpm) > 1 {
+			t.Errorf("bpm %v: estimated %v", tt.bpm, a.Bpm)
+		}
+		if len(a.Onsets) != len(clicks) {
+			t.Errorf("bpm %v: found %d onsets, want %d", tt.bpm, len(a.Onsets), len(clicks))
+		}
+	}
+}
+
+func TestAnalyzeOnsetsAndBands(t *testing.T) {
+	bands := []struct {
+		freq float64
+		band Band
+	}{
+		{80, BandKick},
+		{400, BandSnare},
+		{4000, BandCymbal},
+		{9000, BandHiHat},
+	}
+
+	// A 120 BPM pattern cycling through the kit on eighth notes.
+	var clicks []click
+	var want []Band
+	for i := range 32 {
+		b := bands[i%len(bands)]
+		clicks = append(clicks, click{0.5 + float64(i)*0.25, b.freq})
+		want = append(want, b.band)
+	}
+
+	a := Analyze(synthesize(clicks, 9), testSampleRate, DefaultOptions())
+	if len(a.Onsets) != len(clicks) {
+		t.Fatalf("found %d onsets, want %d", len(a.Onsets), len(clicks))
+	}
+
+	for i, o := range a.Onsets {
+		if math.Abs(o.Time-clicks[i].time) > 0.03 {
+			t.Errorf("onset %d at %.3fs, want %.3fs", i, o.Time, clicks[i].time)
+		}
+		if o.Band != want[i] {
+			t.Errorf("onset %d in band %v, want %v", i, o.Band, want[i])
+		}
+	}
+}
+
+func TestBuildSong(t *testing.T) {
+	clicks := []click{}
+	for i := range 16 {
+		freq := 80.0
+		if i%2 == 1 {
+			freq = 400
+		}
+		clicks = append(clicks, click{1 + float64(i)*0.5, freq})
+	}
+
+	a := Analyze(synthesize(clicks, 10), testSampleRate, DefaultOptions())
+	song := BuildSong(a, "Clicks", "clicks.ogg", 4)
+
+	if song.Bpm != 120 {
+		t.Fatalf("bpm = %d, want 120", song.Bpm)
+	}
+	if song.Duration != 10 {
+		t.Errorf("duration = %v, want 10", song.Duration)
+	}
+	if len(song.Notes) != len(clicks) {
+		t.Fatalf("got %d notes, want %d", len(song.Notes), len(clicks))
+	}
+
+	// Beats land on whole numbers, one every half second from 1s.
+	for i, n := range song.Notes {
+		wantDirection := "left"
+		if i%2 == 1 {
+			wantDirection = "down"
+		}
+		if n.Direction != wantDirection {
+			t.Errorf("note %d direction = %q, want %q", i, n.Direction, wantDirection)
+		}
+		if want := float64(2 + i); math.Abs(n.Onset-want) > 0.05 {
+			t.Errorf("note %d onset = %v, want %v", i, n.Onset, want)
+		}
+	}
+}
+
+func TestBuildSongSkipsDuplicates(t *testing.T) {
+	a := &Analysis{
+		Bpm:      120,
+		Duration: 2,
+		Onsets: []Onset{
+			{Time: 0.5, Band: BandKick},
+			{Time: 0.52, Band: BandKick},
+			{Time: 0.52, Band: BandSnare},
+		},
+	}
+
+	song := BuildSong(a, "", "", 4)
+	if len(song.Notes) != 2 {
+		t.Fatalf("got %d notes, want 2: %v", len(song.Notes), song.Notes)
+	}
+}
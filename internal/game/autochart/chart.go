@@ -0,0 +1,71 @@
+package gameautochart
+
+import (
+	"math"
+	"sort"
+)
+
+// laneBands are the lanes notes of each band are placed on.
+var laneBands = [bandCount]string{
+	BandKick:   "left",
+	BandSnare:  "down",
+	BandHiHat:  "up",
+	BandCymbal: "right",
+}
+
+// Note is a chart note in the song JSON format.
+type Note struct {
+	Direction string  `json:"direction"`
+	Onset     float64 `json:"onset"`
+}
+
+// Song is a draft song in the song JSON format.
+type Song struct {
+	Title    string  `json:"title"`
+	Filename string  `json:"filename"`
+	Bpm      int     `json:"bpm"`
+	Duration float64 `json:"duration"`
+	Notes    []Note  `json:"notes"`
+}
+
+// BuildSong turns an analysis into a draft chart. Onsets are snapped to
+// 1/subdivision of a beat on the grid that starts at the analysis phase.
+func BuildSong(a *Analysis, title, filename string, subdivision int) *Song {
+	song := &Song{
+		Title:    title,
+		Filename: filename,
+		Bpm:      int(math.Round(a.Bpm)),
+		Duration: math.Ceil(a.Duration),
+		Notes:    []Note{},
+	}
+	if song.Bpm <= 0 {
+		return song
+	}
+
+	// The chart is timed with the rounded tempo, as that is what the game uses.
+	beatsPerSecond := float64(song.Bpm) / 60
+	sub := float64(max(subdivision, 1))
+	phase := a.Phase * beatsPerSecond
+	phase -= math.Floor(phase*sub) / sub
+
+	seen := make(map[Note]bool)
+	for _, o := range a.Onsets {
+		beat := math.Round((o.Time*beatsPerSecond-phase)*sub)/sub + phase
+		if beat < 0 {
+			continue
+		}
+
+		n := Note{Direction: laneBands[o.Band], Onset: math.Round(beat*1000) / 1000}
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		song.Notes = append(song.Notes, n)
+	}
+
+	sort.SliceStable(song.Notes, func(i, j int) bool {
+		return song.Notes[i].Onset < song.Notes[j].Onset
+	})
+
+	return song
+}
@@ -0,0 +1,77 @@
+package gameplay
+
+import "fmt"
+
+// A chart has between MinLanes and MaxLanes lanes.
+const (
+	MinLanes = 4
+	MaxLanes = 7
+)
+
+// Lane is one column of the track. Notes name the lane they are played on in
+// their direction.
+type Lane struct {
+	ID string `json:"id"`
+	// Arrow is the arrow drawn on the keys of the lane: left, down, up or right.
+	// Lanes without an arrow show their label instead.
+	Arrow string `json:"arrow,omitempty"`
+	Label string `json:"label,omitempty"`
+	// Width is the share of the track the lane takes, relative to the other
+	// lanes. It defaults to 1.
+	Width float64 `json:"width,omitempty"`
+	// Bindings are the inputs that play the lane, like "key:S" or "pad:X". Lanes
+	// without bindings play with the controls of their position.
+	Bindings []string `json:"bindings,omitempty"`
+}
+
+// DefaultLanes are the four arrow lanes of charts that don't declare their
+// own.
+func DefaultLanes() []Lane {
+	return []Lane{
+		{ID: "left", Arrow: "left"},
+		{ID: "down", Arrow: "down"},
+		{ID: "up", Arrow: "up"},
+		{ID: "right", Arrow: "right"},
+	}
+}
+
+// LaneIDs returns the IDs of the lanes, in track order.
+func LaneIDs(lanes []Lane) []string {
+	ids := make([]string, len(lanes))
+	for i, l := range lanes {
+		ids[i] = l.ID
+	}
+	return ids
+}
+
+// validateLanes checks the lane definitions of a chart, and that each note is
+// played on one of them.
+func validateLanes(lanes []Lane, notes []*Note) error {
+	if len(lanes) < MinLanes || len(lanes) > MaxLanes {
+		return fmt.Errorf("chart has %d lanes, want %d to %d", len(lanes), MinLanes, MaxLanes)
+	}
+
+	ids := make(map[string]bool, len(lanes))
+	for _, l := range lanes {
+		if l.ID == "" || l.ID == BonusLane {
+			return fmt.Errorf("invalid lane id %q", l.ID)
+		}
+		if ids[l.ID] {
+			return fmt.Errorf("duplicate lane %q", l.ID)
+		}
+		if l.Width < 0 {
+			return fmt.Errorf("lane %q has a negative width", l.ID)
+		}
+		ids[l.ID] = true
+	}
+
+	for _, n := range notes {
+		if n == nil {
+			return fmt.Errorf("chart has an empty note")
+		}
+		if !ids[n.Direction] {
+			return fmt.Errorf("note at beat %g is on unknown lane %q", n.Onset, n.Direction)
+		}
+	}
+	return nil
+}
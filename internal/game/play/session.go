@@ -0,0 +1,265 @@
+package gameplay
+
+import (
+	gamereplay "github.com/leandroatallah/drummer/internal/game/replay"
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+)
+
+const (
+	// ThermometerLimit is the highest value of the thermometer.
+	ThermometerLimit = 25
+
+	// BonusLane is the lane of the events that activate the bonus.
+	BonusLane = "bonus"
+	// BonusPhraseCharge is how many beats of bonus a cleared phrase is worth,
+	// and BonusLimit how many beats the meter holds.
+	BonusPhraseCharge = 8
+	BonusLimit        = 32
+	// bonusMultiplier multiplies the combo multiplier while the bonus is active.
+	bonusMultiplier = 2
+)
+
+// Session judges the lane input of a play of a song and keeps its score.
+type Session struct {
+	Song        *Song
+	Judge       *gamerhythm.Judge
+	Tally       gamerhythm.Tally
+	Score       int
+	Streak      int
+	MaxStreak   int
+	Thermometer int
+	// FailMode makes the thermometer a life gauge, see EnableFailMode. Failed
+	// is set once it runs out, at the song time FailedAt.
+	FailMode bool
+	Failed   bool
+	FailedAt float64
+	// BonusMeter holds the beats of bonus charged by clearing phrases. Once
+	// activated, the bonus lasts until the beat BonusEnd.
+	BonusMeter     float64
+	BonusEnd       float64
+	bonusActivated bool
+
+	// OnEffect, if set, is called after every effect is applied.
+	OnEffect func(effect gamerhythm.Effect)
+
+	// now is the song time of the judgement being applied.
+	now float64
+	// phrases tracks the bonus phrases of the chart, and notePhrases the phrase
+	// of each note in one.
+	phrases     []phraseProgress
+	notePhrases map[*Note]int
+}
+
+// phraseProgress counts the notes of a phrase left to play. A phrase is broken
+// by any note that breaks the streak.
+type phraseProgress struct {
+	remaining int
+	broken    bool
+}
+
+func NewSession(song *Song, judge *gamerhythm.Judge) *Session {
+	s := &Session{Song: song, Judge: judge, notePhrases: make(map[*Note]int)}
+
+	s.phrases = make([]phraseProgress, len(song.Phrases))
+	for _, n := range song.Notes {
+		for i, p := range song.Phrases {
+			if p.Contains(n.Onset) {
+				s.notePhrases[n] = i
+				s.phrases[i].remaining++
+				break
+			}
+		}
+	}
+	return s
+}
+
+// EnableFailMode fills the thermometer and makes it follow the life effect of
+// each judgement. The session fails when it empties.
+func (s *Session) EnableFailMode() {
+	s.FailMode = true
+	s.Thermometer = ThermometerLimit
+}
+
+// HandleEvent judges a lane event at the song time it happened, so a replay
+// is judged exactly like the session it was recorded from.
+func (s *Session) HandleEvent(e gamereplay.Event) {
+	s.Settle(e.Time)
+	s.now = e.Time
+	switch {
+	case e.Lane == BonusLane:
+		if e.Pressed {
+			s.ActivateBonus()
+		}
+	case e.Pressed:
+		s.handlePress(e)
+	default:
+		s.handleRelease(e)
+	}
+}
+
+func (s *Session) handlePress(e gamereplay.Event) {
+	note := s.Song.ClosestPending(e.Lane, s.Song.BeatAt(e.Time))
+	if note == nil {
+		s.Mistake()
+		return
+	}
+
+	grade, ok := s.Judge.Classify(s.Song.OffsetAt(note.Onset, e.Time))
+	if !ok {
+		s.Mistake()
+		return
+	}
+
+	note.Judged = true
+	note.Holding = note.IsHold()
+	s.ApplyGrade(grade)
+	if !note.IsHold() {
+		s.playPhraseNote(note, grade)
+	}
+}
+
+// handleRelease judges the release of hold notes whose head was hit.
+func (s *Session) handleRelease(e gamereplay.Event) {
+	for _, v := range s.Song.Playing() {
+		n := v.Note
+		if !n.Holding || n.Direction != e.Lane {
+			continue
+		}
+
+		n.Holding = false
+		if grade, ok := s.Judge.Classify(s.Song.OffsetAt(n.End(), e.Time)); ok {
+			s.ApplyGrade(grade)
+			s.playPhraseNote(n, grade)
+			continue
+		}
+
+		// Released too early: only the held part counts.
+		held := (s.Song.BeatAt(e.Time) - n.Onset) / n.Length
+		s.Tally.AddBrokenHold()
+		s.ApplyEffect(s.Judge.Partial(held))
+		s.playPhraseNote(n, gamerhythm.GradeMiss)
+	}
+}
+
+// Settle judges the notes that can no longer be played at the given song time.
+// Notes past the last timing window are misses, and holds still held when they
+// end are completed, as if released right on their end: the player did hold
+// the whole note, so letting go later is not a mistake.
+func (s *Session) Settle(seconds float64) {
+	s.now = seconds
+	maxOffset := s.Judge.MaxOffset()
+	for _, v := range s.Song.Playing() {
+		n := v.Note
+		switch {
+		case !n.Judged && s.Song.OffsetAt(n.Onset, seconds) > maxOffset:
+			n.Judged = true
+			s.ApplyGrade(gamerhythm.GradeMiss)
+			s.playPhraseNote(n, gamerhythm.GradeMiss)
+		case n.Holding && s.Song.OffsetAt(n.End(), seconds) >= 0:
+			n.Holding = false
+			grade, _ := s.Judge.Classify(0)
+			s.ApplyGrade(grade)
+			s.playPhraseNote(n, grade)
+		}
+	}
+}
+
+// playPhraseNote counts a note that was fully played, with the grade of its
+// last judgement, towards its bonus phrase. A phrase played without breaking
+// the streak charges the bonus meter.
+func (s *Session) playPhraseNote(n *Note, grade gamerhythm.Grade) {
+	i, ok := s.notePhrases[n]
+	if !ok || s.phrases[i].remaining == 0 {
+		return
+	}
+
+	p := &s.phrases[i]
+	p.remaining--
+	if s.Judge.Effect(grade).Combo == gamerhythm.ComboBreak {
+		p.broken = true
+	}
+	if p.remaining == 0 && !p.broken {
+		s.BonusMeter = min(s.BonusMeter+BonusPhraseCharge, BonusLimit)
+	}
+}
+
+// ActivateBonus spends the bonus meter, if the bonus is not already active.
+func (s *Session) ActivateBonus() {
+	if s.BonusMeter == 0 || s.BonusActive(s.now) {
+		return
+	}
+	s.BonusEnd = s.Song.BeatAt(s.now) + s.BonusMeter
+	s.BonusMeter = 0
+	s.bonusActivated = true
+}
+
+// BonusActive reports whether the bonus is active at a song time. It is never
+// active before it was activated, even in the count-in before the song.
+func (s *Session) BonusActive(seconds float64) bool {
+	return s.bonusActivated && s.Song.BeatAt(seconds) < s.BonusEnd
+}
+
+// Multiplier is the score multiplier at a song time: the combo tier of the
+// streak, doubled while the bonus is active.
+func (s *Session) Multiplier(seconds float64) int {
+	multiplier := s.Judge.Multiplier(s.Streak)
+	if s.BonusActive(seconds) {
+		multiplier *= bonusMultiplier
+	}
+	return multiplier
+}
+
+// ApplyGrade counts a judged note and applies the effect of its grade.
+func (s *Session) ApplyGrade(grade gamerhythm.Grade) {
+	s.Tally.Add(grade)
+	s.ApplyEffect(s.Judge.Effect(grade))
+}
+
+// Mistake punishes a key press that had no note to hit.
+func (s *Session) Mistake() {
+	s.ApplyEffect(s.Judge.Effect(gamerhythm.GradeMiss))
+}
+
+// ApplyEffect applies an effect. Its score is multiplied once the streak is
+// updated, so the hit that reaches a combo tier already counts for it.
+func (s *Session) ApplyEffect(effect gamerhythm.Effect) {
+	switch effect.Combo {
+	case gamerhythm.ComboIncrement:
+		s.Streak++
+		s.MaxStreak = max(s.MaxStreak, s.Streak)
+	case gamerhythm.ComboBreak:
+		s.Streak = 0
+	}
+
+	s.Score += effect.Score * s.Multiplier(s.now)
+
+	if s.FailMode {
+		s.Thermometer += effect.Life
+	} else {
+		s.Thermometer += effect.Thermometer
+	}
+	if s.Thermometer > ThermometerLimit {
+		s.Thermometer = ThermometerLimit
+	}
+	if s.Thermometer < 0 {
+		s.Thermometer = 0
+	}
+	if s.FailMode && s.Thermometer == 0 && !s.Failed {
+		s.Failed = true
+		s.FailedAt = s.now
+	}
+
+	if s.OnEffect != nil {
+		s.OnEffect(effect)
+	}
+}
+
+// Step judges the lane events of a frame, then the notes left behind at the
+// current song time, and moves the notes on the track.
+func (s *Session) Step(events []gamereplay.Event) {
+	for _, e := range events {
+		s.HandleEvent(e)
+	}
+	s.Settle(s.Song.Seconds())
+	s.Song.Update()
+}
@@ -0,0 +1,619 @@
+package gameplay
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	gamereplay "github.com/leandroatallah/drummer/internal/game/replay"
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+)
+
+// fakeClock is a song clock moved by hand.
+type fakeClock struct {
+	now time.Duration
+}
+
+func (c *fakeClock) Current() time.Duration {
+	return c.now
+}
+
+// testBpm makes a beat last half a second.
+const testBpm = 120
+
+func songData(t testing.TB, notes ...Note) []byte {
+	t.Helper()
+	return songDataWithPhrases(t, nil, notes...)
+}
+
+func songDataWithPhrases(t testing.TB, phrases []Phrase, notes ...Note) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"title":    "Test",
+		"filename": "test.ogg",
+		"bpm":      testBpm,
+		"duration": 10,
+		"notes":    notes,
+		"phrases":  phrases,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// simulate plays a song frame by frame with a fake clock and feeds the events,
+// which must be in time order, to the session once their time has come, like
+// a replay.
+func simulate(t *testing.T, data []byte, events []gamereplay.Event, fps int, seconds float64) *Session {
+	t.Helper()
+	return simulateMode(t, data, events, fps, seconds, false)
+}
+
+// simulateMode is simulate with fail mode on or off.
+func simulateMode(t *testing.T, data []byte, events []gamereplay.Event, fps int, seconds float64, failMode bool) *Session {
+	t.Helper()
+
+	clock := &fakeClock{}
+	song, err := NewSongFromData(data, "", clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := NewSession(song, gamerhythm.NewJudge(gamerhythm.DefaultJudgementConfig()))
+	if failMode {
+		session.EnableFailMode()
+	}
+	player := gamereplay.NewPlayer(&gamereplay.Replay{Events: events})
+
+	frame := time.Second / time.Duration(fps)
+	for clock.now = 0; clock.now.Seconds() <= seconds; clock.now += frame {
+		session.Step(player.Poll(song.Seconds()))
+	}
+	return session
+}
+
+// beat returns the song time of a beat plus an offset in milliseconds.
+func beat(b float64, offsetMs int) float64 {
+	return b*60/testBpm + float64(offsetMs)/1000
+}
+
+func press(lane string, seconds float64) gamereplay.Event {
+	return gamereplay.Event{Time: seconds, Lane: lane, Pressed: true}
+}
+
+func release(lane string, seconds float64) gamereplay.Event {
+	return gamereplay.Event{Time: seconds, Lane: lane}
+}
+
+// taps presses the left lane on each beat from first on, shifted by the given
+// offsets.
+func taps(first float64, offsetsMs ...int) []gamereplay.Event {
+	var events []gamereplay.Event
+	for i, offset := range offsetsMs {
+		t := beat(first+float64(i), offset)
+		events = append(events, press("left", t), release("left", t+0.05))
+	}
+	return events
+}
+
+type outcome struct {
+	score       int
+	streak      int
+	maxStreak   int
+	thermometer int
+	grades      map[gamerhythm.Grade]int
+	brokenHolds int
+}
+
+func checkOutcome(t *testing.T, s *Session, want outcome) {
+	t.Helper()
+
+	if s.Score != want.score {
+		t.Errorf("score = %d, want %d", s.Score, want.score)
+	}
+	if s.Streak != want.streak {
+		t.Errorf("streak = %d, want %d", s.Streak, want.streak)
+	}
+	if s.MaxStreak != want.maxStreak {
+		t.Errorf("max streak = %d, want %d", s.MaxStreak, want.maxStreak)
+	}
+	if s.Thermometer != want.thermometer {
+		t.Errorf("thermometer = %d, want %d", s.Thermometer, want.thermometer)
+	}
+	for _, g := range gamerhythm.Grades {
+		if got := s.Tally.Count(g); got != want.grades[g] {
+			t.Errorf("%v count = %d, want %d", g, got, want.grades[g])
+		}
+	}
+	if got := s.Tally.BrokenHolds(); got != want.brokenHolds {
+		t.Errorf("broken holds = %d, want %d", got, want.brokenHolds)
+	}
+}
+
+func TestSessionTaps(t *testing.T) {
+	// Four left notes on beats 2 to 5.
+	notes := []Note{
+		{Direction: "left", Onset: 2},
+		{Direction: "left", Onset: 3},
+		{Direction: "left", Onset: 4},
+		{Direction: "left", Onset: 5},
+	}
+
+	tests := []struct {
+		name   string
+		events []gamereplay.Event
+		want   outcome
+	}{
+		{
+			name:   "all perfect",
+			events: taps(2, 0, 0, 0, 0),
+			want: outcome{
+				score: 20, streak: 4, maxStreak: 4, thermometer: 4,
+				grades: map[gamerhythm.Grade]int{gamerhythm.GradePerfect: 4},
+			},
+		},
+		{
+			name:   "early and late perfects",
+			events: taps(2, -40, 40, -20, 20),
+			want: outcome{
+				score: 20, streak: 4, maxStreak: 4, thermometer: 4,
+				grades: map[gamerhythm.Grade]int{gamerhythm.GradePerfect: 4},
+			},
+		},
+		{
+			name:   "late greats",
+			events: taps(2, 60, 60, 60, 60),
+			want: outcome{
+				score: 16, streak: 4, maxStreak: 4, thermometer: 4,
+				grades: map[gamerhythm.Grade]int{gamerhythm.GradeGreat: 4},
+			},
+		},
+		{
+			name:   "no input",
+			events: nil,
+			want: outcome{
+				grades: map[gamerhythm.Grade]int{gamerhythm.GradeMiss: 4},
+			},
+		},
+		{
+			name:   "bad breaks the streak",
+			events: taps(2, 100, -100, 160, 0),
+			want: outcome{
+				score: 9, streak: 1, maxStreak: 2, thermometer: 1,
+				grades: map[gamerhythm.Grade]int{
+					gamerhythm.GradeGood:    2,
+					gamerhythm.GradeBad:     1,
+					gamerhythm.GradePerfect: 1,
+				},
+			},
+		},
+		{
+			name:   "missing the last notes",
+			events: taps(2, 0, 0),
+			want: outcome{
+				score: 10, streak: 0, maxStreak: 2, thermometer: 0,
+				grades: map[gamerhythm.Grade]int{
+					gamerhythm.GradePerfect: 2,
+					gamerhythm.GradeMiss:    2,
+				},
+			},
+		},
+		{
+			name: "stray press breaks the streak",
+			events: append(append(taps(2, 0, 0),
+				press("down", beat(3.5, 0)),
+				release("down", beat(3.5, 50)),
+			), taps(4, 0, 0)...),
+			want: outcome{
+				score: 20, streak: 2, maxStreak: 2, thermometer: 2,
+				grades: map[gamerhythm.Grade]int{gamerhythm.GradePerfect: 4},
+			},
+		},
+		{
+			name: "too early press is a mistake",
+			events: append([]gamereplay.Event{
+				press("left", beat(2, -300)),
+				release("left", beat(2, -250)),
+			}, taps(3, 0, 0, 0)...),
+			want: outcome{
+				score: 15, streak: 3, maxStreak: 3, thermometer: 3,
+				grades: map[gamerhythm.Grade]int{
+					gamerhythm.GradeMiss:    1,
+					gamerhythm.GradePerfect: 3,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := simulate(t, songData(t, notes...), tt.events, 60, 4)
+			checkOutcome(t, s, tt.want)
+		})
+	}
+}
+
+func TestSessionHolds(t *testing.T) {
+	// A left hold from beat 2 to beat 4.
+	notes := []Note{{Direction: "left", Onset: 2, Length: 2}}
+
+	tests := []struct {
+		name   string
+		events []gamereplay.Event
+		want   outcome
+	}{
+		{
+			name:   "held to the end",
+			events: []gamereplay.Event{press("left", beat(2, 0)), release("left", beat(4, 0))},
+			want: outcome{
+				score: 10, streak: 2, maxStreak: 2, thermometer: 2,
+				grades: map[gamerhythm.Grade]int{gamerhythm.GradePerfect: 2},
+			},
+		},
+		{
+			name:   "released halfway",
+			events: []gamereplay.Event{press("left", beat(2, 0)), release("left", beat(3, 0))},
+			want: outcome{
+				score: 8, streak: 0, maxStreak: 1, thermometer: 0,
+				grades:      map[gamerhythm.Grade]int{gamerhythm.GradePerfect: 1},
+				brokenHolds: 1,
+			},
+		},
+		{
+			name:   "held past the end completes the hold",
+			events: []gamereplay.Event{press("left", beat(2, 0)), release("left", beat(5, 0))},
+			want: outcome{
+				score: 10, streak: 2, maxStreak: 2, thermometer: 2,
+				grades: map[gamerhythm.Grade]int{gamerhythm.GradePerfect: 2},
+			},
+		},
+		{
+			name:   "released early within the window",
+			events: []gamereplay.Event{press("left", beat(2, 0)), release("left", beat(4, -100))},
+			want: outcome{
+				score: 7, streak: 2, maxStreak: 2, thermometer: 1,
+				grades: map[gamerhythm.Grade]int{
+					gamerhythm.GradePerfect: 1,
+					gamerhythm.GradeGood:    1,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := simulate(t, songData(t, notes...), tt.events, 60, 4)
+			checkOutcome(t, s, tt.want)
+		})
+	}
+}
+
+func TestSessionReplayRoundTrip(t *testing.T) {
+	notes := []Note{
+		{Direction: "left", Onset: 2},
+		{Direction: "down", Onset: 3, Length: 2},
+		{Direction: "up", Onset: 4},
+		{Direction: "left", Onset: 6},
+	}
+	data := songData(t, notes...)
+	events := []gamereplay.Event{
+		press("left", beat(2, 20)),
+		release("left", beat(2, 80)),
+		press("down", beat(3, -60)),
+		press("up", beat(4, 100)),
+		release("up", beat(4, 150)),
+		release("down", beat(4.5, 0)),
+		press("right", beat(5, 0)),
+		release("right", beat(5, 50)),
+	}
+
+	// Record the session as the play scene does, and encode it as it is saved.
+	played := simulate(t, data, events, 60, 5)
+	replay := gamereplay.New("test.json", "", data, 0, 0)
+	for _, e := range events {
+		replay.Record(e)
+	}
+	encoded, err := json.Marshal(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded gamereplay.Replay
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Matches(data, "") {
+		t.Fatal("the decoded replay doesn't match the chart")
+	}
+
+	// Judging the replay at another frame rate gives the same result.
+	watched := simulate(t, data, decoded.Events, 144, 5)
+	if watched.Score != played.Score || watched.MaxStreak != played.MaxStreak || watched.Tally != played.Tally {
+		t.Errorf("replay scored %d, max streak %d, %+v; the session scored %d, max streak %d, %+v",
+			watched.Score, watched.MaxStreak, watched.Tally, played.Score, played.MaxStreak, played.Tally)
+	}
+	if played.Tally.Total() == 0 {
+		t.Error("nothing was judged")
+	}
+}
+
+func TestSessionJudgesInRealTime(t *testing.T) {
+	data := songData(t, Note{Direction: "left", Onset: 2})
+
+	// The tap is 30ms late in song time, which is 60ms in real time at half
+	// speed: past the Perfect window.
+	tests := []struct {
+		rate  float64
+		grade gamerhythm.Grade
+	}{
+		{1, gamerhythm.GradePerfect},
+		{0.5, gamerhythm.GradeGreat},
+	}
+	for _, tt := range tests {
+		clock := &fakeClock{}
+		song, err := NewSongFromData(data, "", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		song.SetRate(tt.rate)
+		session := NewSession(song, gamerhythm.NewJudge(gamerhythm.DefaultJudgementConfig()))
+		player := gamereplay.NewPlayer(&gamereplay.Replay{Events: taps(2, 30)})
+
+		for clock.now = 0; song.Seconds() <= beat(4, 0); clock.now += time.Second / 60 {
+			session.Step(player.Poll(song.Seconds()))
+		}
+		if got := session.Tally.Count(tt.grade); got != 1 {
+			t.Errorf("rate %v: %d %v, want 1", tt.rate, got, tt.grade)
+		}
+	}
+}
+
+func TestSessionThermometerLimit(t *testing.T) {
+	var notes []Note
+	var offsets []int
+	for i := range ThermometerLimit + 5 {
+		notes = append(notes, Note{Direction: "left", Onset: float64(2 + i)})
+		offsets = append(offsets, 0)
+	}
+
+	s := simulate(t, songData(t, notes...), taps(2, offsets...), 60, 20)
+	if s.Thermometer != ThermometerLimit {
+		t.Errorf("thermometer = %d, want %d", s.Thermometer, ThermometerLimit)
+	}
+	if s.MaxStreak != len(notes) {
+		t.Errorf("max streak = %d, want %d", s.MaxStreak, len(notes))
+	}
+}
+
+func TestSessionFailMode(t *testing.T) {
+	// One left note per beat from beat 2 on.
+	var notes []Note
+	for i := range 20 {
+		notes = append(notes, Note{Direction: "left", Onset: float64(2 + i)})
+	}
+	data := songData(t, notes...)
+
+	t.Run("missing drains the gauge", func(t *testing.T) {
+		s := simulateMode(t, data, nil, 60, 10, true)
+		if !s.Failed {
+			t.Fatalf("not failed, thermometer = %d", s.Thermometer)
+		}
+		if s.Thermometer != 0 {
+			t.Errorf("thermometer = %d, want 0", s.Thermometer)
+		}
+		// The default miss drains 3 of 25, so the ninth miss, on beat 10, empties it.
+		if want := beat(10, 0); s.FailedAt < want || s.FailedAt > want+0.25 {
+			t.Errorf("failed at %.3fs, want just after %.3fs", s.FailedAt, want)
+		}
+	})
+
+	t.Run("hits keep the gauge full", func(t *testing.T) {
+		offsets := make([]int, len(notes))
+		s := simulateMode(t, data, taps(2, offsets...), 60, 12, true)
+		if s.Failed || s.Thermometer != ThermometerLimit {
+			t.Errorf("failed = %v, thermometer = %d; want a full gauge", s.Failed, s.Thermometer)
+		}
+	})
+
+	t.Run("no fail", func(t *testing.T) {
+		s := simulateMode(t, data, nil, 60, 12, false)
+		if s.Failed {
+			t.Error("failed without fail mode")
+		}
+	})
+}
+
+func TestSessionComboMultiplier(t *testing.T) {
+	var notes []Note
+	offsets := make([]int, 12)
+	for i := range offsets {
+		notes = append(notes, Note{Direction: "left", Onset: float64(2 + i)})
+	}
+
+	// The default tiers double the score from the tenth hit in a row.
+	s := simulate(t, songData(t, notes...), taps(2, offsets...), 60, 8)
+	if want := 9*5 + 3*5*2; s.Score != want {
+		t.Errorf("score = %d, want %d", s.Score, want)
+	}
+	if got := s.Multiplier(s.Song.Seconds()); got != 2 {
+		t.Errorf("multiplier = %d, want 2", got)
+	}
+}
+
+func TestSessionBonusPhrase(t *testing.T) {
+	// A phrase over the notes of beats 2 and 3, then two more notes.
+	phrases := []Phrase{{Start: 2, End: 3}}
+	notes := []Note{
+		{Direction: "left", Onset: 2},
+		{Direction: "left", Onset: 3},
+		{Direction: "left", Onset: 4},
+		{Direction: "left", Onset: 5},
+	}
+	activate := []gamereplay.Event{press(BonusLane, beat(3.5, 0))}
+
+	tests := []struct {
+		name   string
+		events []gamereplay.Event
+		score  int
+	}{
+		{
+			name:   "cleared phrase doubles the next notes",
+			events: append(append(taps(2, 0, 0), activate...), taps(4, 0, 0)...),
+			score:  5 + 5 + 10 + 10,
+		},
+		{
+			name:   "broken phrase doesn't charge",
+			events: append(append(taps(2, 0, 200), activate...), taps(4, 0, 0)...),
+			score:  5 + 5 + 5,
+		},
+		{
+			name:   "not activated",
+			events: taps(2, 0, 0, 0, 0),
+			score:  20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := simulate(t, songDataWithPhrases(t, phrases, notes...), tt.events, 60, 4)
+			if s.Score != tt.score {
+				t.Errorf("score = %d, want %d", s.Score, tt.score)
+			}
+		})
+	}
+}
+
+func TestSessionBonusInactiveBeforeActivation(t *testing.T) {
+	song, err := NewSongFromData(songData(t, Note{Direction: "left", Onset: 2}), "", &fakeClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSession(song, gamerhythm.NewJudge(gamerhythm.DefaultJudgementConfig()))
+
+	// The count-in before the song has negative song times.
+	for _, seconds := range []float64{-2, -0.1, 0, 1} {
+		if s.BonusActive(seconds) {
+			t.Errorf("bonus active at %vs without being activated", seconds)
+		}
+	}
+	if got := s.Multiplier(-1); got != 1 {
+		t.Errorf("multiplier in the count-in = %d, want 1", got)
+	}
+}
+
+// A replay judges the same at any frame rate, since events carry their time.
+func TestSessionFrameRateIndependent(t *testing.T) {
+	notes := []Note{
+		{Direction: "left", Onset: 2},
+		{Direction: "down", Onset: 2.5},
+		{Direction: "left", Onset: 3, Length: 1},
+		{Direction: "up", Onset: 4.5},
+	}
+	events := []gamereplay.Event{
+		press("left", beat(2, 30)),
+		release("left", beat(2, 80)),
+		press("down", beat(2.5, -100)),
+		release("down", beat(2.5, -50)),
+		press("left", beat(3, 10)),
+		release("left", beat(3.6, 0)),
+		press("up", beat(4.5, 170)),
+		release("up", beat(4.5, 200)),
+	}
+
+	want := simulate(t, songData(t, notes...), events, 60, 4)
+	for _, fps := range []int{24, 30, 144, 240} {
+		got := simulate(t, songData(t, notes...), events, fps, 4)
+		if got.Score != want.Score || got.MaxStreak != want.MaxStreak || got.Thermometer != want.Thermometer || got.Tally != want.Tally {
+			t.Errorf("%d fps: score %d, max streak %d, thermometer %d, tally %+v; want %d, %d, %d, %+v",
+				fps, got.Score, got.MaxStreak, got.Thermometer, got.Tally,
+				want.Score, want.MaxStreak, want.Thermometer, want.Tally)
+		}
+	}
+}
+
+func TestSongLanes(t *testing.T) {
+	kit := []Lane{
+		{ID: "hihat", Label: "H"}, {ID: "snare", Label: "S"}, {ID: "tom1", Label: "T"},
+		{ID: "kick", Label: "K", Width: 2}, {ID: "tom2", Label: "T"}, {ID: "floor", Label: "F"},
+		{ID: "crash", Label: "C"},
+	}
+	chart := func(lanes []Lane, notes ...Note) []byte {
+		data, err := json.Marshal(map[string]any{
+			"title":    "Test",
+			"filename": "test.ogg",
+			"bpm":      testBpm,
+			"notes":    notes,
+			"lanes":    lanes,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	t.Run("default lanes", func(t *testing.T) {
+		song, err := NewSongFromData(songData(t, Note{Direction: "left", Onset: 1}), "", &fakeClock{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := LaneIDs(song.Lanes); len(got) != 4 || got[0] != "left" || got[3] != "right" {
+			t.Errorf("lanes = %v, want the four arrows", got)
+		}
+	})
+
+	t.Run("drum kit", func(t *testing.T) {
+		data := chart(kit, Note{Direction: "kick", Onset: 2}, Note{Direction: "crash", Onset: 2})
+		events := []gamereplay.Event{
+			press("kick", beat(2, 0)), press("crash", beat(2, 0)),
+			release("kick", beat(2, 50)), release("crash", beat(2, 50)),
+		}
+		s := simulate(t, data, events, 60, 3)
+		if got := s.Tally.Count(gamerhythm.GradePerfect); got != 2 {
+			t.Errorf("perfect count = %d, want 2", got)
+		}
+	})
+
+	invalid := []struct {
+		name string
+		data []byte
+	}{
+		{"too few lanes", chart(kit[:3])},
+		{"too many lanes", chart(append(kit, Lane{ID: "ride"}))},
+		{"duplicate lane", chart([]Lane{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "a"}})},
+		{"unknown note lane", chart(kit, Note{Direction: "left", Onset: 1})},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSongFromData(tt.data, "", &fakeClock{}); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
+
+// A large visual offset scrolls notes off the track before they are late
+// enough to be missed: they must still be judged.
+func TestSessionMissesNotesScrolledOff(t *testing.T) {
+	clock := &fakeClock{}
+	song, err := NewSongFromData(songData(t,
+		Note{Direction: "left", Onset: 2},
+		Note{Direction: "down", Onset: 3},
+	), "", clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	song.SetOffsets(0, 600*time.Millisecond)
+	s := NewSession(song, gamerhythm.NewJudge(gamerhythm.DefaultJudgementConfig()))
+
+	for clock.now < 3*time.Second {
+		clock.now += time.Second / 60
+		s.Step(nil)
+	}
+
+	if got := s.Tally.Count(gamerhythm.GradeMiss); got != 2 {
+		t.Errorf("miss count = %d, want every note missed", got)
+	}
+	if got := len(song.Playing()); got != 0 {
+		t.Errorf("%d notes left on the track, want the missed notes removed", got)
+	}
+}
@@ -0,0 +1,451 @@
+package gameplay
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+	gamesongs "github.com/leandroatallah/drummer/internal/game/songs"
+)
+
+const (
+	// defaultScrollSpeed is used by charts that don't set their own speed.
+	defaultScrollSpeed = 2.0
+)
+
+// Clock tells the playback position of the song audio.
+type Clock interface {
+	Current() time.Duration
+}
+
+// Seeker is a clock that can jump to another position.
+type Seeker interface {
+	Clock
+	SetPosition(offset time.Duration) error
+}
+
+type Note struct {
+	Direction string  `json:"direction"`
+	Onset     float64 `json:"onset"`
+	// Length is the duration of a hold note in beats. Tap notes have no length.
+	Length float64 `json:"length,omitempty"`
+	// Judged is set once the note was hit or missed.
+	Judged bool `json:"-"`
+	// Holding is set while the head of a hold note was hit and its key is
+	// still down.
+	Holding bool `json:"-"`
+}
+
+// IsHold reports whether the note must be held until Onset + Length.
+func (n *Note) IsHold() bool {
+	return n.Length > 0
+}
+
+// End is the beat where the note finishes. It is the onset for tap notes.
+func (n *Note) End() float64 {
+	return n.Onset + n.Length
+}
+
+// Phrase is a range of beats of a chart. Hitting every note in it charges the
+// bonus meter.
+type Phrase struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// Contains reports whether a beat is part of the phrase.
+func (p Phrase) Contains(beat float64) bool {
+	return beat >= p.Start && beat <= p.End
+}
+
+// validatePhrases rejects phrases that end before they start or overlap, since
+// a note counts toward one phrase only.
+func validatePhrases(phrases []Phrase) error {
+	for i, p := range phrases {
+		if p.Start < 0 || p.End < p.Start {
+			return fmt.Errorf("invalid phrase from beat %g to %g", p.Start, p.End)
+		}
+		for _, q := range phrases[:i] {
+			if p.Start <= q.End && q.Start <= p.End {
+				return fmt.Errorf("phrase from beat %g overlaps the one from beat %g", p.Start, q.Start)
+			}
+		}
+	}
+	return nil
+}
+
+// Chart is one difficulty of a song, with its own notes and scroll speed.
+type Chart struct {
+	Difficulty string   `json:"difficulty"`
+	Level      int      `json:"level,omitempty"`
+	Speed      float64  `json:"speed,omitempty"`
+	Notes      []*Note  `json:"notes"`
+	Phrases    []Phrase `json:"phrases,omitempty"`
+	// Lanes are the columns of the track. Charts without lanes use
+	// DefaultLanes.
+	Lanes []Lane `json:"lanes,omitempty"`
+	// Events are the visual effects of the chart.
+	Events []ChartEvent `json:"events,omitempty"`
+}
+
+type Song struct {
+	Title    string  `json:"title"`
+	Filename string  `json:"filename"`
+	Bpm      int     `json:"bpm"`
+	Duration float64 `json:"duration"`
+	// Notes is the chart of songs that have a single difficulty. Once the song is
+	// loaded, it holds the notes of the chosen chart.
+	Notes   []*Note      `json:"notes,omitempty"`
+	Phrases []Phrase     `json:"phrases,omitempty"`
+	Lanes   []Lane       `json:"lanes,omitempty"`
+	Events  []ChartEvent `json:"events,omitempty"`
+	Charts  []*Chart     `json:"charts,omitempty"`
+	Chart   *Chart       `json:"-"`
+	// TempoMap is optional. Without it the song plays at Bpm in 4/4.
+	TempoMap *gamerhythm.TempoMapData `json:"tempo_map,omitempty"`
+
+	// Lookahead is the number of beats, at the base tempo, a note takes to
+	// scroll down the track.
+	Lookahead float64 `json:"-"`
+
+	clock Clock
+	tempo *gamerhythm.TempoMap
+	// noteIndex is the chart index of the next note to enter the track.
+	noteIndex int
+	// window holds the notes on the track, and playing is the slice Playing
+	// returns, reused every frame.
+	window  noteWindow
+	playing []NoteView
+	// laneIndex finds the position of a lane by its ID. laneNotes lists the
+	// chart indexes of the notes of each lane, and laneHeads the first of them
+	// that may still be hit.
+	laneIndex map[string]int
+	laneNotes [][]int
+	laneHeads []int
+	// rate is the playback speed of the audio. The clock runs in real time, so
+	// the song advances rate seconds for every second of the clock.
+	rate float64
+
+	// audioOffset and visualOffset come from the latency calibration.
+	audioOffset  time.Duration
+	visualOffset time.Duration
+}
+
+// NewSongFromData loads a song and picks the chart of the given difficulty.
+// The first chart is used when there is no chart with that difficulty.
+func NewSongFromData(data []byte, difficulty string, clock Clock) (*Song, error) {
+	var song Song
+	if err := json.Unmarshal(data, &song); err != nil {
+		return nil, err
+	}
+	song.clock = clock
+	song.rate = 1
+	tempo, err := gamerhythm.NewTempoMap(float64(song.Bpm), song.TempoMap)
+	if err != nil {
+		return nil, err
+	}
+	song.tempo = tempo
+
+	if len(song.Charts) == 0 {
+		song.Charts = []*Chart{{Difficulty: gamesongs.DifficultyNormal, Notes: song.Notes, Phrases: song.Phrases, Lanes: song.Lanes, Events: song.Events}}
+	}
+	song.Chart = song.Charts[0]
+	for _, c := range song.Charts {
+		if c.Difficulty == difficulty {
+			song.Chart = c
+		}
+	}
+	if song.Chart.Speed <= 0 {
+		song.Chart.Speed = defaultScrollSpeed
+	}
+	if err := song.Chart.validate(); err != nil {
+		return nil, err
+	}
+	song.Notes = song.Chart.Notes
+	song.Phrases = song.Chart.Phrases
+	song.Lanes = song.Chart.lanes()
+	song.Events = song.Chart.Events
+	sort.SliceStable(song.Notes, func(i, j int) bool {
+		return song.Notes[i].Onset < song.Notes[j].Onset
+	})
+
+	song.laneIndex = make(map[string]int, len(song.Lanes))
+	for i, l := range song.Lanes {
+		song.laneIndex[l.ID] = i
+	}
+	song.laneNotes = make([][]int, len(song.Lanes))
+	song.laneHeads = make([]int, len(song.Lanes))
+	song.ResetWindow(0)
+	song.Lookahead = 4 / song.Chart.Speed
+
+	return &song, nil
+}
+
+// ValidateSong checks every chart of a song file, where NewSongFromData only
+// checks the chart it plays. The song registry runs it to leave out the songs
+// that can't be played.
+func ValidateSong(data []byte) error {
+	var song Song
+	if err := json.Unmarshal(data, &song); err != nil {
+		return err
+	}
+	if _, err := gamerhythm.NewTempoMap(float64(song.Bpm), song.TempoMap); err != nil {
+		return err
+	}
+
+	if len(song.Charts) == 0 {
+		chart := Chart{Notes: song.Notes, Phrases: song.Phrases, Lanes: song.Lanes, Events: song.Events}
+		return chart.validate()
+	}
+	for _, c := range song.Charts {
+		if err := c.validate(); err != nil {
+			return fmt.Errorf("%s chart: %w", c.Difficulty, err)
+		}
+	}
+	return nil
+}
+
+// lanes returns the lanes of the chart, or the default ones.
+func (c *Chart) lanes() []Lane {
+	if len(c.Lanes) == 0 {
+		return DefaultLanes()
+	}
+	return c.Lanes
+}
+
+func (c *Chart) validate() error {
+	lanes := c.lanes()
+	if err := validateLanes(lanes, c.Notes); err != nil {
+		return err
+	}
+	if err := validatePhrases(c.Phrases); err != nil {
+		return err
+	}
+	return validateEvents(c.Events, lanes)
+}
+
+func (s *Song) Update() error {
+	// Remove old notes from the track, once judged: with a large visual
+	// offset or a fast song, a note can scroll off before it is missed.
+	render := s.RenderPositionInBPM()
+	s.window.removeIf(func(v *NoteView) bool {
+		return render > v.Note.End()+1 && v.Note.Judged && !v.Note.Holding // 1 beat buffer
+	})
+
+	// Get playing notes
+	for s.noteIndex < len(s.Notes) {
+		n := s.Notes[s.noteIndex]
+		if s.ScrollProgress(n.Onset) < 0 {
+			// The upcoming notes are not ready yet.
+			break
+		}
+		s.window.push(NoteView{
+			Note:  n,
+			Index: s.noteIndex,
+			Lane:  s.laneIndex[n.Direction],
+			Bonus: s.InPhrase(n.Onset),
+		})
+		s.noteIndex++
+	}
+
+	return nil
+}
+
+// GetPositionInBPM returns the song position, in beats, that the player is
+// hearing. It follows the tempo map and is used for judgement.
+func (s *Song) GetPositionInBPM() float64 {
+	return s.tempo.BeatAt(s.Seconds())
+}
+
+// RenderPositionInBPM returns the song position, in beats, that should be on
+// screen. It is ahead of GetPositionInBPM by the display latency.
+func (s *Song) RenderPositionInBPM() float64 {
+	return s.tempo.BeatAt(s.Seconds() + s.visualOffset.Seconds()*s.rate)
+}
+
+// BeatAt returns the song position, in beats, at the given song seconds.
+func (s *Song) BeatAt(seconds float64) float64 {
+	return s.tempo.BeatAt(seconds)
+}
+
+// SetOffsets sets the audio and visual latency measured by the calibration.
+func (s *Song) SetOffsets(audioOffset, visualOffset time.Duration) {
+	s.audioOffset = audioOffset
+	s.visualOffset = visualOffset
+}
+
+// SetRate sets the playback speed of the audio the clock follows. Latency
+// offsets stay in real time.
+func (s *Song) SetRate(rate float64) {
+	s.rate = rate
+}
+
+func (s *Song) Rate() float64 {
+	return s.rate
+}
+
+// Seconds is the clock position minus the audio latency, in song time. It is
+// the clock notes are judged with.
+func (s *Song) Seconds() float64 {
+	return (s.clock.Current() - s.audioOffset).Seconds() * s.rate
+}
+
+// TimingOffset returns how far the current song position is from the onset,
+// in real time. It is negative while the onset is still ahead.
+func (s *Song) TimingOffset(onset float64) time.Duration {
+	return s.OffsetAt(onset, s.Seconds())
+}
+
+// OffsetAt is TimingOffset for the song position at the given seconds. Song
+// time runs at the playback rate, so the offset is divided by it: timing
+// windows stay the same in milliseconds when practicing at a slower speed.
+func (s *Song) OffsetAt(onset, seconds float64) time.Duration {
+	return time.Duration((seconds - s.tempo.SecondsAt(onset)) / s.rate * float64(time.Second))
+}
+
+// Playing returns the notes on the track in chart order, so they are judged in
+// the same order on every run. The slice is only valid until the next call.
+func (s *Song) Playing() []NoteView {
+	s.playing = s.window.appendTo(s.playing[:0])
+	return s.playing
+}
+
+// ClosestPending returns the note of a lane on the track, not judged yet,
+// nearest to a song position.
+func (s *Song) ClosestPending(lane string, beat float64) *Note {
+	i, ok := s.laneIndex[lane]
+	if !ok {
+		return nil
+	}
+
+	notes := s.laneNotes[i]
+	head := s.laneHeads[i]
+	for head < len(notes) && s.Notes[notes[head]].Judged {
+		head++
+	}
+	s.laneHeads[i] = head
+
+	var closest *Note
+	for _, index := range notes[head:] {
+		if index >= s.noteIndex {
+			// Not on the track yet
+			break
+		}
+
+		n := s.Notes[index]
+		if n.Judged {
+			continue
+		}
+		if closest != nil && n.Onset-beat >= math.Abs(closest.Onset-beat) {
+			// Notes are sorted, the next ones are further away.
+			break
+		}
+		if closest == nil || math.Abs(n.Onset-beat) < math.Abs(closest.Onset-beat) {
+			closest = n
+		}
+	}
+	return closest
+}
+
+// ScrollProgress tells how far a beat has travelled down the track, from 0
+// when it appears on top to 1 when it reaches the arrows. Notes scroll in real
+// time, so a tempo change does not change the scroll speed.
+func (s *Song) ScrollProgress(beat float64) float64 {
+	lookahead := s.Lookahead * 60 / float64(s.Bpm)
+	remaining := s.tempo.SecondsAt(beat) - s.tempo.SecondsAt(s.RenderPositionInBPM())
+	return 1 - remaining/lookahead
+}
+
+// InPhrase reports whether a beat is part of a bonus phrase.
+func (s *Song) InPhrase(beat float64) bool {
+	for _, p := range s.Phrases {
+		if p.Contains(beat) {
+			return true
+		}
+	}
+	return false
+}
+
+// BeatInMeasure returns the current beat counted from the start of its measure.
+func (s *Song) BeatInMeasure() float64 {
+	_, beat := s.tempo.MeasureAt(s.RenderPositionInBPM())
+	return beat
+}
+
+// BeatDuration returns how long, in real time, the beat starting at the given
+// song position lasts.
+func (s *Song) BeatDuration(beat float64) time.Duration {
+	seconds := (s.tempo.SecondsAt(beat+1) - s.tempo.SecondsAt(beat)) / s.rate
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func (s *Song) GetTicksPerBeat() float64 {
+	return (60 * 60) / s.tempo.BpmAt(s.GetPositionInBPM())
+}
+
+// SetScrollSpeed scales the scroll speed of the chart, so notes come down
+// faster or slower.
+func (s *Song) SetScrollSpeed(multiplier float64) {
+	s.Lookahead = 4 / (s.Chart.Speed * multiplier)
+}
+
+// SetPositionInBPM seeks the clock, when it can seek, to a song position and
+// makes the notes from there on the next ones to be played.
+func (s *Song) SetPositionInBPM(beats float64) {
+	if seeker, ok := s.clock.(Seeker); ok {
+		seeker.SetPosition(s.ClockPosition(beats))
+	}
+
+	s.ResetWindow(beats)
+}
+
+// ClockPosition returns the clock position where the player hears a song
+// position.
+func (s *Song) ClockPosition(beats float64) time.Duration {
+	seconds := s.tempo.SecondsAt(beats) / s.rate
+	return time.Duration(seconds*float64(time.Second)) + s.audioOffset
+}
+
+// ResetWindow makes the notes from beats on the next ones to be played. The
+// notes, which must be sorted by onset, may have changed since the last call.
+func (s *Song) ResetWindow(beats float64) {
+	s.noteIndex = sort.Search(len(s.Notes), func(i int) bool {
+		return s.Notes[i].Onset >= beats
+	})
+	s.window.clear()
+
+	for i := range s.laneNotes {
+		s.laneNotes[i] = s.laneNotes[i][:0]
+	}
+	for i, n := range s.Notes {
+		lane := s.laneIndex[n.Direction]
+		s.laneNotes[lane] = append(s.laneNotes[lane], i)
+	}
+	s.resetHeads()
+}
+
+// resetHeads moves the head of each lane back to its first note on the track,
+// or that didn't enter the track yet.
+func (s *Song) resetHeads() {
+	first := s.noteIndex
+	if s.window.size > 0 {
+		first = s.window.at(0).Index
+	}
+	for i, notes := range s.laneNotes {
+		s.laneHeads[i] = sort.SearchInts(notes, first)
+	}
+}
+
+// ClearJudgements makes the notes from beats on playable again.
+func (s *Song) ClearJudgements(beats float64) {
+	for _, n := range s.Notes {
+		if n.Onset >= beats {
+			n.Judged = false
+			n.Holding = false
+		}
+	}
+	s.resetHeads()
+}
@@ -0,0 +1,217 @@
+package gameplay
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	gamereplay "github.com/leandroatallah/drummer/internal/game/replay"
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+)
+
+func TestNoteWindow(t *testing.T) {
+	var w noteWindow
+	for i := range 40 {
+		w.push(NoteView{Index: i})
+	}
+
+	// Drop the front notes, and every third of the others.
+	w.removeIf(func(v *NoteView) bool {
+		return v.Index < 5 || v.Index%3 == 0
+	})
+	for i := 40; i < 45; i++ {
+		w.push(NoteView{Index: i})
+	}
+
+	got := w.appendTo(nil)
+	prev := -1
+	for _, v := range got {
+		if v.Index <= prev || (v.Index < 40 && (v.Index < 5 || v.Index%3 == 0)) {
+			t.Fatalf("window = %v, want the kept notes in order", got)
+		}
+		prev = v.Index
+	}
+	if want := 40 - 5 - 12 + 5; len(got) != want {
+		t.Errorf("window has %d notes, want %d", len(got), want)
+	}
+}
+
+func TestSongWindowFollowsSeeks(t *testing.T) {
+	clock := &fakeClock{}
+	song, err := NewSongFromData(songData(t,
+		Note{Direction: "left", Onset: 1},
+		Note{Direction: "down", Onset: 2},
+		Note{Direction: "left", Onset: 3},
+	), "", clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// At beat 1, the track shows the next two beats.
+	clock.now = 500 * time.Millisecond
+	song.Update()
+	if got := len(song.Playing()); got != 3 {
+		t.Fatalf("%d notes on the track, want 3", got)
+	}
+	if n := song.ClosestPending("left", 2.9); n == nil || n.Onset != 3 {
+		t.Errorf("closest left note to beat 2.9 = %v, want the one at beat 3", n)
+	}
+
+	song.ResetWindow(2.5)
+	song.Update()
+	if got := song.Playing(); len(got) != 1 || got[0].Index != 2 {
+		t.Errorf("notes on the track after a seek = %v, want the note at beat 3", got)
+	}
+	if n := song.ClosestPending("left", 1); n == nil || n.Onset != 3 {
+		t.Errorf("closest left note after a seek = %v, want the one at beat 3", n)
+	}
+}
+
+// denseChart is a chart of count notes, four per beat over the four lanes,
+// with a hold every eighth note.
+func denseChart(tb testing.TB, count int) []byte {
+	lanes := []string{"left", "down", "up", "right"}
+	notes := make([]Note, count)
+	for i := range notes {
+		notes[i] = Note{Direction: lanes[i%len(lanes)], Onset: 2 + float64(i)/4}
+		if i%8 == 0 {
+			notes[i].Length = 1
+		}
+	}
+	return songData(tb, notes...)
+}
+
+// frameRunner plays a chart frame by frame, hitting every other note.
+type frameRunner struct {
+	clock   *fakeClock
+	session *Session
+	events  []gamereplay.Event
+	next    int
+	end     time.Duration
+}
+
+func newFrameRunner(tb testing.TB, data []byte) *frameRunner {
+	clock := &fakeClock{}
+	song, err := NewSongFromData(data, "", clock)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	r := &frameRunner{
+		clock:   clock,
+		session: NewSession(song, gamerhythm.NewJudge(gamerhythm.DefaultJudgementConfig())),
+	}
+	for i, n := range song.Notes {
+		if i%2 == 0 {
+			continue
+		}
+		at := beat(n.Onset, 0)
+		r.events = append(r.events, press(n.Direction, at), release(n.Direction, beat(n.End(), 0)+0.01))
+	}
+	// Releases come after presses of later notes.
+	sort.SliceStable(r.events, func(i, j int) bool { return r.events[i].Time < r.events[j].Time })
+
+	last := song.Notes[len(song.Notes)-1]
+	r.end = time.Duration((beat(last.End(), 0) + 1) * float64(time.Second))
+	return r
+}
+
+// frame moves the song one frame on, starting over once it is over.
+func (r *frameRunner) frame() {
+	r.clock.now += time.Second / 60
+	if r.clock.now > r.end {
+		r.clock.now = 0
+		r.next = 0
+		r.session.Song.ResetWindow(0)
+		r.session.Song.ClearJudgements(0)
+	}
+
+	now := r.session.Song.Seconds()
+	first := r.next
+	for r.next < len(r.events) && r.events[r.next].Time <= now {
+		r.next++
+	}
+	r.session.Step(r.events[first:r.next])
+	r.session.Song.Playing()
+}
+
+// warmUp plays the chart once, so the window reached its largest size.
+func (r *frameRunner) warmUp() {
+	for range r.end / (time.Second / 60) {
+		r.frame()
+	}
+}
+
+func TestFrameDoesNotAllocate(t *testing.T) {
+	r := newFrameRunner(t, denseChart(t, 2000))
+	r.warmUp()
+
+	if allocs := testing.AllocsPerRun(1000, r.frame); allocs != 0 {
+		t.Errorf("%v allocations per frame, want 0", allocs)
+	}
+}
+
+func BenchmarkFrame(b *testing.B) {
+	r := newFrameRunner(b, denseChart(b, 2000))
+	r.warmUp()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		r.frame()
+	}
+}
+
+func TestValidateSong(t *testing.T) {
+	if err := ValidateSong(songData(t, Note{Direction: "left", Onset: 1})); err != nil {
+		t.Errorf("single chart song: %v", err)
+	}
+
+	// Every chart is checked, not only the one a difficulty picks.
+	charts := `{"title": "Test", "filename": "test.ogg", "bpm": 120, "charts": [
+		{"difficulty": "Easy", "notes": [{"direction": "left", "onset": 1}]},
+		{"difficulty": "Hard", "notes": [{"direction": "kick", "onset": 1}]}
+	]}`
+	if _, err := NewSongFromData([]byte(charts), "Easy", &fakeClock{}); err != nil {
+		t.Fatalf("the Easy chart is valid: %v", err)
+	}
+	err := ValidateSong([]byte(charts))
+	if err == nil || !strings.Contains(err.Error(), "Hard") {
+		t.Errorf("err = %v, want the Hard chart reported", err)
+	}
+
+	invalid := map[string]string{
+		"not json":            `notes`,
+		"zero bpm":            `{"bpm": 0, "notes": []}`,
+		"empty note":          `{"bpm": 120, "notes": [null]}`,
+		"too few lanes":       `{"bpm": 120, "lanes": [{"id": "a"}, {"id": "b"}], "notes": []}`,
+		"unknown event":       `{"bpm": 120, "notes": [], "events": [{"type": "confetti", "beat": 1}]}`,
+		"reversed phrase":     `{"bpm": 120, "notes": [], "phrases": [{"start": 8, "end": 4}]}`,
+		"overlapping phrases": `{"bpm": 120, "notes": [], "phrases": [{"start": 0, "end": 4}, {"start": 4, "end": 8}]}`,
+		"broken chart phrase": `{"bpm": 120, "charts": [{"difficulty": "Hard", "notes": [], "phrases": [{"start": -1, "end": 4}]}]}`,
+	}
+	for name, data := range invalid {
+		if err := ValidateSong([]byte(data)); err == nil {
+			t.Errorf("%s: validated, want an error", name)
+		}
+	}
+}
+
+func TestSongData(t *testing.T) {
+	files, err := filepath.Glob("../../../assets/songs/*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no songs found: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateSong(data); err != nil {
+			t.Errorf("%s: %v", filepath.Base(file), err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package gameplay
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// EventType is the effect of a chart event.
+type EventType string
+
+const (
+	// EventFlash turns the background to Color.
+	EventFlash EventType = "flash"
+	// EventShake shakes the screen by up to Strength pixels.
+	EventShake EventType = "shake"
+	// EventHighlight paints Lane with Color.
+	EventHighlight EventType = "highlight"
+	// EventText pops Text up over the track.
+	EventText EventType = "text"
+	// EventPose changes the drummer to Pose, until the next pose event. An
+	// empty pose gives the drummer its own moves back.
+	EventPose EventType = "pose"
+	// EventIllustration swaps the illustration for Image, until the next
+	// illustration event. An empty image puts the song illustration back.
+	EventIllustration EventType = "illustration"
+)
+
+// EventColors are the palette colors flashes and highlights can use.
+var EventColors = []string{"light", "medium", "dark"}
+
+// DrummerPoses are the poses a pose event can give the drummer.
+var DrummerPoses = []string{"idle", "rock", "error"}
+
+// ChartEvent is a visual effect of the chart, on a beat.
+type ChartEvent struct {
+	Beat float64   `json:"beat"`
+	Type EventType `json:"type"`
+	// Length is how many beats a flash, shake, highlight or text lasts. It
+	// defaults to one beat.
+	Length   float64 `json:"length,omitempty"`
+	Color    string  `json:"color,omitempty"`
+	Lane     string  `json:"lane,omitempty"`
+	Strength float64 `json:"strength,omitempty"`
+	Text     string  `json:"text,omitempty"`
+	Pose     string  `json:"pose,omitempty"`
+	// Image is the file name of an illustration in the images assets.
+	Image string `json:"image,omitempty"`
+}
+
+// End is the beat where the effect is over.
+func (e *ChartEvent) End() float64 {
+	if e.Length <= 0 {
+		return e.Beat + 1
+	}
+	return e.Beat + e.Length
+}
+
+// Progress tells how far into the effect a beat is, from 0 to 1.
+func (e *ChartEvent) Progress(beat float64) float64 {
+	return min(max((beat-e.Beat)/(e.End()-e.Beat), 0), 1)
+}
+
+// validateEvents checks the events of a chart with lanes.
+func validateEvents(events []ChartEvent, lanes []Lane) error {
+	for _, e := range events {
+		switch e.Type {
+		case EventFlash:
+			if !slices.Contains(EventColors, e.Color) {
+				return fmt.Errorf("flash at beat %g has unknown color %q", e.Beat, e.Color)
+			}
+		case EventHighlight:
+			if e.Color != "" && !slices.Contains(EventColors, e.Color) {
+				return fmt.Errorf("highlight at beat %g has unknown color %q", e.Beat, e.Color)
+			}
+			if !slices.ContainsFunc(lanes, func(l Lane) bool { return l.ID == e.Lane }) {
+				return fmt.Errorf("highlight at beat %g is on unknown lane %q", e.Beat, e.Lane)
+			}
+		case EventPose:
+			if e.Pose != "" && !slices.Contains(DrummerPoses, e.Pose) {
+				return fmt.Errorf("pose at beat %g is unknown: %q", e.Beat, e.Pose)
+			}
+		case EventShake, EventText, EventIllustration:
+		default:
+			return fmt.Errorf("event at beat %g has unknown type %q", e.Beat, e.Type)
+		}
+		if e.Length < 0 {
+			return fmt.Errorf("event at beat %g has a negative length", e.Beat)
+		}
+	}
+	return nil
+}
+
+// Timeline follows the events of a chart along the song. It is moved to the
+// beat on screen every frame, and jumps with the song when it seeks, so the
+// effects on at a beat are always the same however the song got there.
+type Timeline struct {
+	// events are sorted by beat, and next is the first one not started yet.
+	events []ChartEvent
+	next   int
+	beat   float64
+	// active are the started effects that are not over, in the order they
+	// started.
+	active []*ChartEvent
+	// pose and image are the last pose and illustration events started.
+	pose  *ChartEvent
+	image *ChartEvent
+}
+
+func NewTimeline(events []ChartEvent) *Timeline {
+	t := &Timeline{events: slices.Clone(events)}
+	sort.SliceStable(t.events, func(i, j int) bool {
+		return t.events[i].Beat < t.events[j].Beat
+	})
+	t.Seek(0)
+	return t
+}
+
+// Update moves the timeline to a beat. Moving back seeks.
+func (t *Timeline) Update(beat float64) {
+	if beat < t.beat {
+		t.Seek(beat)
+		return
+	}
+	t.beat = beat
+
+	t.active = slices.DeleteFunc(t.active, func(e *ChartEvent) bool {
+		return e.End() <= beat
+	})
+	for t.next < len(t.events) && t.events[t.next].Beat <= beat {
+		t.start(&t.events[t.next])
+		t.next++
+	}
+}
+
+// Seek puts the timeline at a beat, with the effects of the events before it
+// that are still on.
+func (t *Timeline) Seek(beat float64) {
+	t.beat = beat
+	t.next = 0
+	t.active = t.active[:0]
+	t.pose, t.image = nil, nil
+	for t.next < len(t.events) && t.events[t.next].Beat <= beat {
+		t.start(&t.events[t.next])
+		t.next++
+	}
+}
+
+// start turns an event on, unless it was over before the current beat, which
+// happens when seeking past it.
+func (t *Timeline) start(e *ChartEvent) {
+	switch {
+	case e.Type == EventPose:
+		t.pose = e
+	case e.Type == EventIllustration:
+		t.image = e
+	case e.End() > t.beat:
+		t.active = append(t.active, e)
+	}
+}
+
+// Active returns the flashes, shakes, highlights and texts that are on, in the
+// order they started.
+func (t *Timeline) Active() []*ChartEvent {
+	return t.active
+}
+
+// Pose is the pose of the drummer, or empty for its own moves.
+func (t *Timeline) Pose() string {
+	if t.pose == nil {
+		return ""
+	}
+	return t.pose.Pose
+}
+
+// Image is the illustration on screen, or empty for the song illustration.
+func (t *Timeline) Image() string {
+	if t.image == nil {
+		return ""
+	}
+	return t.image.Image
+}
@@ -0,0 +1,91 @@
+package gameplay
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func activeTypes(t *Timeline) []EventType {
+	var types []EventType
+	for _, e := range t.Active() {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestTimeline(t *testing.T) {
+	timeline := NewTimeline([]ChartEvent{
+		{Beat: 8, Type: EventHighlight, Lane: "left", Length: 8},
+		{Beat: 2, Type: EventFlash, Color: "light"},
+		{Beat: 4, Type: EventPose, Pose: "rock"},
+		{Beat: 10, Type: EventText, Text: "SOLO", Length: 0.5},
+		{Beat: 12, Type: EventPose},
+	})
+
+	steps := []struct {
+		beat   float64
+		active []EventType
+		pose   string
+	}{
+		{1, nil, ""},
+		{2.5, []EventType{EventFlash}, ""},
+		{3, nil, ""},
+		{5, nil, "rock"},
+		{10.2, []EventType{EventHighlight, EventText}, "rock"},
+		{11, []EventType{EventHighlight}, "rock"},
+		{13, []EventType{EventHighlight}, ""},
+		// Seeking back
+		{2.1, []EventType{EventFlash}, ""},
+		// and forward, past the text, into the highlight.
+		{11, []EventType{EventHighlight}, "rock"},
+		{16, nil, ""},
+	}
+	for _, step := range steps {
+		timeline.Update(step.beat)
+		if got := activeTypes(timeline); !slices.Equal(got, step.active) {
+			t.Errorf("effects at beat %g = %v, want %v", step.beat, got, step.active)
+		}
+		if got := timeline.Pose(); got != step.pose {
+			t.Errorf("pose at beat %g = %q, want %q", step.beat, got, step.pose)
+		}
+	}
+}
+
+func TestSongEvents(t *testing.T) {
+	load := func(events ...ChartEvent) error {
+		data, err := json.Marshal(map[string]any{
+			"title":  "Test",
+			"bpm":    testBpm,
+			"notes":  []Note{{Direction: "left", Onset: 1}},
+			"events": events,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewSongFromData(data, "", &fakeClock{})
+		return err
+	}
+
+	if err := load(
+		ChartEvent{Beat: 1, Type: EventShake, Strength: 2},
+		ChartEvent{Beat: 2, Type: EventHighlight, Lane: "up", Color: "dark"},
+		ChartEvent{Beat: 3, Type: EventIllustration, Image: "illustration-dark.png"},
+	); err != nil {
+		t.Errorf("valid events: %v", err)
+	}
+
+	invalid := map[string]ChartEvent{
+		"unknown type":    {Beat: 1, Type: "confetti"},
+		"flash color":     {Beat: 1, Type: EventFlash, Color: "red"},
+		"highlight lane":  {Beat: 1, Type: EventHighlight, Lane: "bonus"},
+		"pose":            {Beat: 1, Type: EventPose, Pose: "dance"},
+		"negative length": {Beat: 1, Type: EventText, Text: "HI", Length: -1},
+		"highlight color": {Beat: 1, Type: EventHighlight, Lane: "left", Color: "red"},
+	}
+	for name, e := range invalid {
+		if err := load(e); err == nil {
+			t.Errorf("%s: loaded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package gameplay
+
+// NoteView is a note on the track, with what is needed to draw it.
+type NoteView struct {
+	Note *Note
+	// Index is the position of the note in the chart, and Lane the position of
+	// its lane on the track.
+	Index int
+	Lane  int
+	// Bonus is set for the notes of bonus phrases.
+	Bonus bool
+}
+
+// noteWindow is a ring buffer of the notes on the track, in chart order. Notes
+// enter at the back as they appear and usually leave from the front. A note
+// that leaves before an earlier one, like a tap after a long hold, is removed
+// without breaking the order. The buffer only grows when more notes than ever
+// are on the track at once.
+type noteWindow struct {
+	buf   []NoteView
+	start int
+	size  int
+}
+
+// minWindowSize is the initial capacity of the window.
+const minWindowSize = 32
+
+func (w *noteWindow) at(k int) *NoteView {
+	return &w.buf[(w.start+k)%len(w.buf)]
+}
+
+func (w *noteWindow) push(v NoteView) {
+	if w.size == len(w.buf) {
+		w.grow()
+	}
+	*w.at(w.size) = v
+	w.size++
+}
+
+func (w *noteWindow) grow() {
+	buf := make([]NoteView, max(len(w.buf)*2, minWindowSize))
+	for k := 0; k < w.size; k++ {
+		buf[k] = *w.at(k)
+	}
+	w.buf, w.start = buf, 0
+}
+
+// removeIf removes the notes done with, keeping the others in order.
+func (w *noteWindow) removeIf(done func(v *NoteView) bool) {
+	for w.size > 0 && done(w.at(0)) {
+		w.start = (w.start + 1) % len(w.buf)
+		w.size--
+	}
+
+	kept := 0
+	for k := 0; k < w.size; k++ {
+		v := w.at(k)
+		if done(v) {
+			continue
+		}
+		if kept != k {
+			*w.at(kept) = *v
+		}
+		kept++
+	}
+	w.size = kept
+}
+
+func (w *noteWindow) clear() {
+	w.start, w.size = 0, 0
+}
+
+// appendTo appends the notes of the window to views, in order.
+func (w *noteWindow) appendTo(views []NoteView) []NoteView {
+	for k := 0; k < w.size; k++ {
+		views = append(views, *w.at(k))
+	}
+	return views
+}
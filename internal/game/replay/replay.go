@@ -0,0 +1,115 @@
+package gamereplay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	gamesave "github.com/leandroatallah/drummer/internal/game/save"
+)
+
+// Version is the version written in new replay files.
+const Version = 1
+
+// Event is a lane key press or release.
+type Event struct {
+	// Time is the song position in seconds, on the judgement clock.
+	Time    float64 `json:"time"`
+	Lane    string  `json:"lane"`
+	Pressed bool    `json:"pressed"`
+}
+
+// Replay is the input of a play session and what is needed to judge it again.
+type Replay struct {
+	Version    int    `json:"version"`
+	SongID     string `json:"song_id"`
+	Difficulty string `json:"difficulty"`
+	// ChartHash tells whether the chart changed since the replay was recorded.
+	ChartHash      string `json:"chart_hash"`
+	AudioOffsetMs  int    `json:"audio_offset_ms"`
+	VisualOffsetMs int    `json:"visual_offset_ms"`
+	// FailMode is set when the session could fail, so it fails the same way.
+	FailMode bool    `json:"fail_mode,omitempty"`
+	Events   []Event `json:"events"`
+}
+
+func New(songID, difficulty string, songData []byte, audioOffsetMs, visualOffsetMs int) *Replay {
+	return &Replay{
+		Version:        Version,
+		SongID:         songID,
+		Difficulty:     difficulty,
+		ChartHash:      ChartHash(songData, difficulty),
+		AudioOffsetMs:  audioOffsetMs,
+		VisualOffsetMs: visualOffsetMs,
+	}
+}
+
+// ChartHash identifies the content of a chart of a song.
+func ChartHash(songData []byte, difficulty string) string {
+	h := sha256.New()
+	h.Write(songData)
+	h.Write([]byte(difficulty))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// FileName is the name of the last replay of a chart in the save manager.
+func FileName(songID, difficulty string) string {
+	return "replay-" + songID + "-" + difficulty + ".json"
+}
+
+// Record adds an event to the replay.
+func (r *Replay) Record(e Event) {
+	r.Events = append(r.Events, e)
+}
+
+// Matches reports whether the replay was recorded on the given chart content.
+func (r *Replay) Matches(songData []byte, difficulty string) bool {
+	return r.ChartHash == ChartHash(songData, difficulty)
+}
+
+// Load reads the last replay of a chart.
+func Load(storage gamesave.Storage, songID, difficulty string) (*Replay, error) {
+	data, err := storage.Load(FileName(songID, difficulty))
+	if err != nil {
+		return nil, err
+	}
+
+	var r Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Version > Version {
+		return nil, fmt.Errorf("replay has a newer version: %d", r.Version)
+	}
+	return &r, nil
+}
+
+// Save writes the replay as the last replay of its chart.
+func (r *Replay) Save(storage gamesave.Storage) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return storage.Save(FileName(r.SongID, r.Difficulty), data)
+}
+
+// Player hands out the events of a replay as the song plays.
+type Player struct {
+	replay *Replay
+	next   int
+}
+
+func NewPlayer(replay *Replay) *Player {
+	return &Player{replay: replay}
+}
+
+// Poll returns the events that happened up to the given song time and were
+// not returned yet.
+func (p *Player) Poll(seconds float64) []Event {
+	start := p.next
+	for p.next < len(p.replay.Events) && p.replay.Events[p.next].Time <= seconds {
+		p.next++
+	}
+	return p.replay.Events[start:p.next]
+}
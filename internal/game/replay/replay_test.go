@@ -0,0 +1,77 @@
+package gamereplay
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leandroatallah/drummer/internal/engine/systems/savemanager"
+)
+
+func TestReplayRoundTrip(t *testing.T) {
+	song := []byte(`{"title": "Test", "bpm": 120}`)
+	r := New("test.json", "Hard", song, -115, 20)
+	r.FailMode = true
+	events := []Event{
+		{Time: 1, Lane: "left", Pressed: true},
+		{Time: 1.05, Lane: "left"},
+		{Time: 1.5, Lane: "bonus", Pressed: true},
+	}
+	for _, e := range events {
+		r.Record(e)
+	}
+
+	storage := savemanager.MemoryStore{}
+	if err := r.Save(storage); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(storage, "test.json", "Hard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, r) {
+		t.Errorf("loaded %+v, want %+v", loaded, r)
+	}
+
+	if !loaded.Matches(song, "Hard") {
+		t.Error("the replay doesn't match its chart")
+	}
+	if loaded.Matches(song, "Easy") || loaded.Matches([]byte(`{"title": "Edited"}`), "Hard") {
+		t.Error("the replay matches another chart")
+	}
+	if _, err := Load(storage, "test.json", "Easy"); err == nil {
+		t.Error("loaded the replay of another difficulty")
+	}
+}
+
+func TestLoadRejectsNewerVersion(t *testing.T) {
+	storage := savemanager.MemoryStore{FileName("test.json", "Hard"): []byte(`{"version": 99, "events": []}`)}
+	if _, err := Load(storage, "test.json", "Hard"); err == nil {
+		t.Error("loaded a replay with a newer version")
+	}
+}
+
+func TestPlayerPoll(t *testing.T) {
+	r := &Replay{Events: []Event{
+		{Time: 0.5, Lane: "left", Pressed: true},
+		{Time: 1, Lane: "left"},
+		{Time: 1, Lane: "down", Pressed: true},
+		{Time: 2, Lane: "down"},
+	}}
+	p := NewPlayer(r)
+
+	polls := []struct {
+		seconds float64
+		events  int
+	}{
+		{0.25, 0},
+		{1, 3},
+		{1.5, 0},
+		{3, 1},
+		{4, 0},
+	}
+	for _, poll := range polls {
+		if got := p.Poll(poll.seconds); len(got) != poll.events {
+			t.Errorf("Poll(%v) returned %d events, want %d", poll.seconds, len(got), poll.events)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package gamerhythm
+
+import (
+	"sort"
+	"time"
+)
+
+// MeasureOffset returns the typical delay between metronome ticks and the
+// player taps. Ticks happen every interval starting at 0 and taps are measured
+// from the same origin. Each tap is matched to its nearest tick and the median
+// delay is used, so a few stray taps don't skew the result. It returns false
+// when there are fewer than minTaps taps.
+func MeasureOffset(taps []time.Duration, interval time.Duration, minTaps int) (time.Duration, bool) {
+	if interval <= 0 || len(taps) == 0 || len(taps) < minTaps {
+		return 0, false
+	}
+
+	offsets := make([]time.Duration, 0, len(taps))
+	for _, tap := range taps {
+		nearest := (tap + interval/2) / interval * interval
+		offsets = append(offsets, tap-nearest)
+	}
+
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	mid := len(offsets) / 2
+	if len(offsets)%2 == 0 {
+		return (offsets[mid-1] + offsets[mid]) / 2, true
+	}
+	return offsets[mid], true
+}
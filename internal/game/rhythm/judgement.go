@@ -0,0 +1,403 @@
+package gamerhythm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Grade is the judgement given to a single hit, from Miss (worst) to Perfect (best).
+type Grade int
+
+const (
+	GradeMiss Grade = iota
+	GradeBad
+	GradeGood
+	GradeGreat
+	GradePerfect
+
+	gradeCount
+)
+
+// Grades lists every grade from best to worst.
+var Grades = []Grade{GradePerfect, GradeGreat, GradeGood, GradeBad, GradeMiss}
+
+func (g Grade) String() string {
+	switch g {
+	case GradePerfect:
+		return "Perfect"
+	case GradeGreat:
+		return "Great"
+	case GradeGood:
+		return "Good"
+	case GradeBad:
+		return "Bad"
+	default:
+		return "Miss"
+	}
+}
+
+// MarshalText writes a grade by name, as the judgement data does.
+func (g Grade) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(g.String())), nil
+}
+
+func (g *Grade) UnmarshalText(text []byte) error {
+	for _, grade := range Grades {
+		if strings.EqualFold(string(text), grade.String()) {
+			*g = grade
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown grade %q", text)
+}
+
+// ComboEffect tells how a grade changes the current streak.
+type ComboEffect int
+
+const (
+	ComboKeep ComboEffect = iota
+	ComboIncrement
+	ComboBreak
+)
+
+var comboEffectNames = []string{
+	ComboKeep:      "keep",
+	ComboIncrement: "increment",
+	ComboBreak:     "break",
+}
+
+func (c ComboEffect) MarshalText() ([]byte, error) {
+	if c < 0 || int(c) >= len(comboEffectNames) {
+		return nil, fmt.Errorf("unknown combo effect %d", c)
+	}
+	return []byte(comboEffectNames[c]), nil
+}
+
+func (c *ComboEffect) UnmarshalText(text []byte) error {
+	for i, name := range comboEffectNames {
+		if strings.EqualFold(string(text), name) {
+			*c = ComboEffect(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown combo effect %q", text)
+}
+
+// Effect is what a grade is worth once it is applied to the play session.
+type Effect struct {
+	Score       int `json:"score"`
+	Thermometer int `json:"thermometer"`
+	// Life replaces Thermometer in fail mode, where the thermometer is a life
+	// gauge: it recovers with good hits and drains with mistakes.
+	Life  int         `json:"life"`
+	Combo ComboEffect `json:"combo"`
+}
+
+// Window is the largest absolute offset from a note onset that still earns Grade.
+type Window struct {
+	Grade  Grade
+	Offset time.Duration
+	Effect Effect
+}
+
+// windowJSON is a window in the judgement data, with its offset in
+// milliseconds.
+type windowJSON struct {
+	Grade    Grade   `json:"grade"`
+	OffsetMs float64 `json:"offset_ms"`
+	Effect   Effect  `json:"effect"`
+}
+
+func (w Window) MarshalJSON() ([]byte, error) {
+	return json.Marshal(windowJSON{
+		Grade:    w.Grade,
+		OffsetMs: float64(w.Offset) / float64(time.Millisecond),
+		Effect:   w.Effect,
+	})
+}
+
+func (w *Window) UnmarshalJSON(data []byte) error {
+	var v windowJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*w = Window{
+		Grade:  v.Grade,
+		Offset: time.Duration(v.OffsetMs * float64(time.Millisecond)),
+		Effect: v.Effect,
+	}
+	return nil
+}
+
+// JudgementConfig holds the timing windows and the effect of a missed note.
+// Windows are in milliseconds so they feel the same at any song BPM.
+type JudgementConfig struct {
+	Windows []Window `json:"windows"`
+	Miss    Effect   `json:"miss"`
+	// HoldBreak is the effect of releasing a hold note before its end. The best
+	// window score, scaled by the held fraction, is added on top of it.
+	HoldBreak Effect `json:"hold_break"`
+	// ComboTiers multiply the score of hits once the streak is long enough.
+	ComboTiers []ComboTier `json:"combo_tiers"`
+}
+
+// ComboTier multiplies the score of hits by Multiplier from a streak of Streak
+// hits on.
+type ComboTier struct {
+	Streak     int `json:"streak"`
+	Multiplier int `json:"multiplier"`
+}
+
+func DefaultJudgementConfig() JudgementConfig {
+	return JudgementConfig{
+		Windows: []Window{
+			{Grade: GradePerfect, Offset: 45 * time.Millisecond, Effect: Effect{Score: 5, Thermometer: 1, Life: 1, Combo: ComboIncrement}},
+			{Grade: GradeGreat, Offset: 90 * time.Millisecond, Effect: Effect{Score: 4, Thermometer: 1, Life: 1, Combo: ComboIncrement}},
+			{Grade: GradeGood, Offset: 135 * time.Millisecond, Effect: Effect{Score: 2, Thermometer: 0, Life: 0, Combo: ComboIncrement}},
+			{Grade: GradeBad, Offset: 180 * time.Millisecond, Effect: Effect{Score: 0, Thermometer: -1, Life: -2, Combo: ComboBreak}},
+		},
+		Miss:      Effect{Score: 0, Thermometer: -2, Life: -3, Combo: ComboBreak},
+		HoldBreak: Effect{Score: 0, Thermometer: -1, Life: -2, Combo: ComboBreak},
+		ComboTiers: []ComboTier{
+			{Streak: 10, Multiplier: 2},
+			{Streak: 20, Multiplier: 3},
+			{Streak: 30, Multiplier: 4},
+		},
+	}
+}
+
+// ParseJudgementConfig reads the judgement data of the game. Fields that are
+// missing keep their default values, and lists given replace the default ones.
+func ParseJudgementConfig(data []byte) (JudgementConfig, error) {
+	cfg := DefaultJudgementConfig()
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return DefaultJudgementConfig(), err
+	}
+	if err := cfg.Validate(); err != nil {
+		return DefaultJudgementConfig(), err
+	}
+	return cfg, nil
+}
+
+// Validate checks that every window has its own grade and a positive offset,
+// and that combo tiers are reachable.
+func (cfg JudgementConfig) Validate() error {
+	if len(cfg.Windows) == 0 {
+		return errors.New("no timing windows")
+	}
+	seen := make(map[Grade]bool)
+	for _, w := range cfg.Windows {
+		if w.Grade == GradeMiss {
+			return errors.New("a window can't give a Miss")
+		}
+		if seen[w.Grade] {
+			return fmt.Errorf("%s has more than one window", w.Grade)
+		}
+		seen[w.Grade] = true
+		if w.Offset <= 0 {
+			return fmt.Errorf("%s window has a non-positive offset", w.Grade)
+		}
+	}
+	for _, t := range cfg.ComboTiers {
+		if t.Streak <= 0 || t.Multiplier < 1 {
+			return fmt.Errorf("invalid combo tier: streak %d, multiplier %d", t.Streak, t.Multiplier)
+		}
+	}
+	return nil
+}
+
+// Judge classifies hit offsets into grades.
+type Judge struct {
+	windows   []Window
+	miss      Effect
+	holdBreak Effect
+	tiers     []ComboTier
+}
+
+func NewJudge(cfg JudgementConfig) *Judge {
+	windows := make([]Window, len(cfg.Windows))
+	copy(windows, cfg.Windows)
+
+	// Keep the tightest window first, so the first match is the best grade.
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].Offset < windows[j].Offset })
+
+	tiers := make([]ComboTier, len(cfg.ComboTiers))
+	copy(tiers, cfg.ComboTiers)
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].Streak < tiers[j].Streak })
+
+	return &Judge{windows: windows, miss: cfg.Miss, holdBreak: cfg.HoldBreak, tiers: tiers}
+}
+
+// Multiplier returns the score multiplier of a streak.
+func (j *Judge) Multiplier(streak int) int {
+	multiplier := 1
+	for _, t := range j.tiers {
+		if streak >= t.Streak {
+			multiplier = t.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// Classify returns the grade for a hit that happened offset away from the note
+// onset. A negative offset means the hit was early. The second return value is
+// false when the offset is outside every window.
+func (j *Judge) Classify(offset time.Duration) (Grade, bool) {
+	abs := time.Duration(math.Abs(float64(offset)))
+	for _, w := range j.windows {
+		if abs <= w.Offset {
+			return w.Grade, true
+		}
+	}
+	return GradeMiss, false
+}
+
+// Effect returns the score, thermometer and combo effect of a grade.
+func (j *Judge) Effect(g Grade) Effect {
+	for _, w := range j.windows {
+		if w.Grade == g {
+			return w.Effect
+		}
+	}
+	return j.miss
+}
+
+// Partial returns the effect of a hold note released early, after holding the
+// given fraction (0 to 1) of its length.
+func (j *Judge) Partial(held float64) Effect {
+	held = math.Max(0, math.Min(1, held))
+
+	effect := j.holdBreak
+	if len(j.windows) > 0 {
+		effect.Score += int(math.Round(float64(j.windows[0].Effect.Score) * held))
+	}
+	return effect
+}
+
+// MaxOffset is the widest window. A note that is later than this is missed.
+func (j *Judge) MaxOffset() time.Duration {
+	if len(j.windows) == 0 {
+		return 0
+	}
+	return j.windows[len(j.windows)-1].Offset
+}
+
+// ClearAccuracy is the lowest accuracy that clears a song, a D.
+const ClearAccuracy = 60
+
+// brokenHoldsName is the name of the broken holds in Counts.
+const brokenHoldsName = "Broken"
+
+// Tally counts how many times each grade was given during a song.
+type Tally struct {
+	grades      [gradeCount]int
+	brokenHolds int
+}
+
+func (t *Tally) Add(g Grade) {
+	t.grades[g]++
+}
+
+func (t *Tally) Count(g Grade) int {
+	return t.grades[g]
+}
+
+// AddBrokenHold counts a hold note that was released before its end.
+func (t *Tally) AddBrokenHold() {
+	t.brokenHolds++
+}
+
+func (t *Tally) BrokenHolds() int {
+	return t.brokenHolds
+}
+
+// Counts returns the grade counts keyed by grade name, with the broken holds
+// under "Broken". It is how tallies are saved.
+func (t *Tally) Counts() map[string]int {
+	counts := make(map[string]int, gradeCount+1)
+	for _, g := range Grades {
+		counts[g.String()] = t.grades[g]
+	}
+	counts[brokenHoldsName] = t.brokenHolds
+	return counts
+}
+
+// TallyFromCounts rebuilds a tally saved with Counts. Unknown names are
+// skipped.
+func TallyFromCounts(counts map[string]int) Tally {
+	var t Tally
+	for _, g := range Grades {
+		t.grades[g] = max(counts[g.String()], 0)
+	}
+	t.brokenHolds = max(counts[brokenHoldsName], 0)
+	return t
+}
+
+// Passed reports whether the judgements are good enough to clear a song.
+func (t *Tally) Passed() bool {
+	return t.Total() > 0 && t.Accuracy() >= ClearAccuracy
+}
+
+// Hits is the number of judgements that were not misses.
+func (t *Tally) Hits() int {
+	return t.Total() - t.Misses()
+}
+
+// Misses counts missed notes and broken holds.
+func (t *Tally) Misses() int {
+	return t.grades[GradeMiss] + t.brokenHolds
+}
+
+// Accuracy is the weighted hit percentage, from 0 to 100. A Perfect is worth a
+// full hit and lower grades are worth less.
+func (t *Tally) Accuracy() float64 {
+	if t.Total() == 0 {
+		return 0
+	}
+
+	points := 0.0
+	for g, c := range t.grades {
+		points += accuracyWeights[g] * float64(c)
+	}
+	return 100 * points / float64(t.Total())
+}
+
+var accuracyWeights = [gradeCount]float64{
+	GradeMiss:    0,
+	GradeBad:     0.2,
+	GradeGood:    0.5,
+	GradeGreat:   0.8,
+	GradePerfect: 1,
+}
+
+// LetterGrade ranks an accuracy percentage from S (best) to F.
+func LetterGrade(accuracy float64) string {
+	switch {
+	case accuracy >= 95:
+		return "S"
+	case accuracy >= 90:
+		return "A"
+	case accuracy >= 80:
+		return "B"
+	case accuracy >= 70:
+		return "C"
+	case accuracy >= ClearAccuracy:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// Total is the number of judgements, including hold releases.
+func (t *Tally) Total() int {
+	total := t.brokenHolds
+	for _, c := range t.grades {
+		total += c
+	}
+	return total
+}
@@ -0,0 +1,243 @@
+package gamerhythm
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	judge := NewJudge(DefaultJudgementConfig())
+
+	tests := []struct {
+		offset time.Duration
+		grade  Grade
+		ok     bool
+	}{
+		{0, GradePerfect, true},
+		{45 * time.Millisecond, GradePerfect, true},
+		{45*time.Millisecond + 1, GradeGreat, true},
+		{90 * time.Millisecond, GradeGreat, true},
+		{90*time.Millisecond + 1, GradeGood, true},
+		{135 * time.Millisecond, GradeGood, true},
+		{135*time.Millisecond + 1, GradeBad, true},
+		{180 * time.Millisecond, GradeBad, true},
+		{180*time.Millisecond + 1, GradeMiss, false},
+		{time.Second, GradeMiss, false},
+		// Early hits are judged like late ones.
+		{-45 * time.Millisecond, GradePerfect, true},
+		{-46 * time.Millisecond, GradeGreat, true},
+		{-180 * time.Millisecond, GradeBad, true},
+		{-181 * time.Millisecond, GradeMiss, false},
+	}
+	for _, tt := range tests {
+		grade, ok := judge.Classify(tt.offset)
+		if grade != tt.grade || ok != tt.ok {
+			t.Errorf("Classify(%v) = %v, %v, want %v, %v", tt.offset, grade, ok, tt.grade, tt.ok)
+		}
+	}
+}
+
+func TestJudgeSortsWindows(t *testing.T) {
+	cfg := JudgementConfig{
+		Windows: []Window{
+			{Grade: GradeGood, Offset: 100 * time.Millisecond},
+			{Grade: GradePerfect, Offset: 20 * time.Millisecond},
+			{Grade: GradeGreat, Offset: 50 * time.Millisecond},
+		},
+	}
+	judge := NewJudge(cfg)
+
+	tests := map[time.Duration]Grade{
+		10 * time.Millisecond:  GradePerfect,
+		-30 * time.Millisecond: GradeGreat,
+		80 * time.Millisecond:  GradeGood,
+	}
+	for offset, want := range tests {
+		if got, _ := judge.Classify(offset); got != want {
+			t.Errorf("Classify(%v) = %v, want %v", offset, got, want)
+		}
+	}
+	if got := judge.MaxOffset(); got != 100*time.Millisecond {
+		t.Errorf("MaxOffset() = %v, want 100ms", got)
+	}
+	if cfg.Windows[0].Grade != GradeGood {
+		t.Error("NewJudge sorted the windows of the config")
+	}
+}
+
+func TestJudgeWithoutWindows(t *testing.T) {
+	judge := NewJudge(JudgementConfig{Miss: Effect{Thermometer: -2}})
+
+	if grade, ok := judge.Classify(0); grade != GradeMiss || ok {
+		t.Errorf("Classify(0) = %v, %v, want Miss, false", grade, ok)
+	}
+	if got := judge.MaxOffset(); got != 0 {
+		t.Errorf("MaxOffset() = %v, want 0", got)
+	}
+	if got := judge.Effect(GradePerfect); got.Thermometer != -2 {
+		t.Errorf("Effect(Perfect) = %+v, want the miss effect", got)
+	}
+	if got := judge.Partial(1); got.Score != 0 {
+		t.Errorf("Partial(1).Score = %d, want 0", got.Score)
+	}
+}
+
+func TestJudgeEffects(t *testing.T) {
+	judge := NewJudge(DefaultJudgementConfig())
+
+	if got := judge.Effect(GradePerfect); got.Score != 5 || got.Combo != ComboIncrement {
+		t.Errorf("Effect(Perfect) = %+v", got)
+	}
+	if got := judge.Effect(GradeMiss); got.Combo != ComboBreak {
+		t.Errorf("Effect(Miss) = %+v, want a combo break", got)
+	}
+	if got := judge.Partial(0.5); got.Score != 3 || got.Combo != ComboBreak {
+		t.Errorf("Partial(0.5) = %+v", got)
+	}
+	if got := judge.Partial(2); got.Score != 5 {
+		t.Errorf("Partial(2).Score = %d, want the held fraction capped at 1", got.Score)
+	}
+
+	multipliers := map[int]int{0: 1, 9: 1, 10: 2, 25: 3, 100: 4}
+	for streak, want := range multipliers {
+		if got := judge.Multiplier(streak); got != want {
+			t.Errorf("Multiplier(%d) = %d, want %d", streak, got, want)
+		}
+	}
+}
+
+func TestTally(t *testing.T) {
+	var tally Tally
+	if tally.Accuracy() != 0 {
+		t.Errorf("empty Accuracy() = %v, want 0", tally.Accuracy())
+	}
+
+	for _, g := range []Grade{GradePerfect, GradePerfect, GradeGood, GradeMiss} {
+		tally.Add(g)
+	}
+	tally.AddBrokenHold()
+
+	if got := tally.Count(GradePerfect); got != 2 {
+		t.Errorf("Count(Perfect) = %d, want 2", got)
+	}
+	if tally.Total() != 5 || tally.Hits() != 3 || tally.Misses() != 2 {
+		t.Errorf("Total, Hits, Misses = %d, %d, %d, want 5, 3, 2", tally.Total(), tally.Hits(), tally.Misses())
+	}
+	// Two Perfects and a Good out of five judgements.
+	if got := tally.Accuracy(); got != 50 {
+		t.Errorf("Accuracy() = %v, want 50", got)
+	}
+	if got := LetterGrade(tally.Accuracy()); got != "F" {
+		t.Errorf("LetterGrade = %s, want F", got)
+	}
+}
+
+func TestTallyCounts(t *testing.T) {
+	var tally Tally
+	for _, g := range []Grade{GradePerfect, GradeGreat, GradeGreat, GradeMiss} {
+		tally.Add(g)
+	}
+	tally.AddBrokenHold()
+
+	counts := tally.Counts()
+	if counts["Great"] != 2 || counts["Broken"] != 1 {
+		t.Errorf("Counts() = %v", counts)
+	}
+	if got := TallyFromCounts(counts); got != tally {
+		t.Errorf("TallyFromCounts(Counts()) = %+v, want %+v", got, tally)
+	}
+	if got := TallyFromCounts(map[string]int{"Perfect": 1, "Flawless": 3}); got.Total() != 1 {
+		t.Errorf("unknown grade names were counted, total = %d", got.Total())
+	}
+}
+
+func TestTallyPassed(t *testing.T) {
+	tests := []struct {
+		counts map[string]int
+		passed bool
+	}{
+		{nil, false},
+		{map[string]int{"Perfect": 6, "Miss": 4}, true},
+		{map[string]int{"Perfect": 5, "Miss": 5}, false},
+		{map[string]int{"Good": 10}, false},
+		{map[string]int{"Great": 10}, true},
+	}
+	for _, tt := range tests {
+		tally := TallyFromCounts(tt.counts)
+		if got := tally.Passed(); got != tt.passed {
+			t.Errorf("Passed() of %v = %v, want %v", tt.counts, got, tt.passed)
+		}
+	}
+}
+
+func TestParseJudgementConfig(t *testing.T) {
+	// Missing fields keep their defaults.
+	cfg, err := ParseJudgementConfig([]byte(`{
+		"windows": [
+			{"grade": "perfect", "offset_ms": 30, "effect": {"score": 10, "life": 2, "combo": "increment"}},
+			{"grade": "good", "offset_ms": 100.5, "effect": {"score": 3, "combo": "keep"}}
+		],
+		"miss": {"life": -5}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Windows) != 2 || cfg.Windows[1].Offset != 100500*time.Microsecond || cfg.Windows[1].Effect.Combo != ComboKeep {
+		t.Errorf("windows = %+v", cfg.Windows)
+	}
+	if cfg.Miss.Life != -5 || cfg.Miss.Combo != ComboBreak {
+		t.Errorf("miss = %+v, want the default miss with life -5", cfg.Miss)
+	}
+	if len(cfg.ComboTiers) != len(DefaultJudgementConfig().ComboTiers) {
+		t.Errorf("combo tiers = %+v, want the defaults", cfg.ComboTiers)
+	}
+
+	judge := NewJudge(cfg)
+	if grade, _ := judge.Classify(-40 * time.Millisecond); grade != GradeGood {
+		t.Errorf("Classify(-40ms) = %v, want Good", grade)
+	}
+
+	invalid := map[string]string{
+		"no windows":      `{"windows": []}`,
+		"unknown grade":   `{"windows": [{"grade": "flawless", "offset_ms": 20}]}`,
+		"miss window":     `{"windows": [{"grade": "miss", "offset_ms": 20}]}`,
+		"same grade":      `{"windows": [{"grade": "good", "offset_ms": 20}, {"grade": "good", "offset_ms": 40}]}`,
+		"zero offset":     `{"windows": [{"grade": "perfect", "offset_ms": 0}]}`,
+		"unknown combo":   `{"miss": {"combo": "double"}}`,
+		"zero multiplier": `{"combo_tiers": [{"streak": 10, "multiplier": 0}]}`,
+		"not json":        `windows`,
+	}
+	for name, data := range invalid {
+		if _, err := ParseJudgementConfig([]byte(data)); err == nil {
+			t.Errorf("%s: parsed, want an error", name)
+		}
+	}
+}
+
+func TestJudgementConfigRoundTrip(t *testing.T) {
+	want := DefaultJudgementConfig()
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseJudgementConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestJudgementData(t *testing.T) {
+	data, err := os.ReadFile("../../../assets/data/judgement.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseJudgementConfig(data); err != nil {
+		t.Errorf("invalid judgement data: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package gamerhythm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// TempoChange sets the BPM from a beat on. When Ramp is true the tempo changes
+// linearly until the next change, which is how a ritardando or accelerando is
+// charted.
+type TempoChange struct {
+	Beat float64 `json:"beat"`
+	Bpm  float64 `json:"bpm"`
+	Ramp bool    `json:"ramp,omitempty"`
+}
+
+// TimeSignature sets the measure length from a beat on. Beats are always
+// quarter notes, so 6/8 has three beats per measure.
+type TimeSignature struct {
+	Beat        float64 `json:"beat"`
+	Numerator   int     `json:"numerator"`
+	Denominator int     `json:"denominator"`
+}
+
+// BeatsPerMeasure returns the measure length in quarter-note beats.
+func (t TimeSignature) BeatsPerMeasure() float64 {
+	if t.Numerator <= 0 || t.Denominator <= 0 {
+		return 4
+	}
+	return float64(t.Numerator) * 4 / float64(t.Denominator)
+}
+
+// TempoMapData is the optional "tempo_map" entry of a song JSON file.
+type TempoMapData struct {
+	Tempos         []TempoChange   `json:"tempos"`
+	TimeSignatures []TimeSignature `json:"time_signatures,omitempty"`
+}
+
+// tempoSegment is a tempo change with its start time and the BPM slope up to
+// the next segment.
+type tempoSegment struct {
+	beat    float64
+	seconds float64
+	bpm     float64
+	slope   float64 // BPM change per beat, 0 for a constant tempo
+}
+
+// measureStart is a time signature with the index of its first measure.
+type measureStart struct {
+	signature TimeSignature
+	measure   int
+}
+
+// TempoMap converts between song beats and audio seconds.
+type TempoMap struct {
+	segments []tempoSegment
+	measures []measureStart
+}
+
+// NewTempoMap builds a tempo map. Songs without tempo map data play at a
+// constant bpm in 4/4. Tempos must be positive and time signatures must have
+// beats, or the conversions would divide by zero.
+func NewTempoMap(bpm float64, data *TempoMapData) (*TempoMap, error) {
+	var changes []TempoChange
+	var signatures []TimeSignature
+	if data != nil {
+		changes = append(changes, data.Tempos...)
+		signatures = append(signatures, data.TimeSignatures...)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Beat < changes[j].Beat })
+	if len(changes) == 0 || changes[0].Beat > 0 {
+		changes = append([]TempoChange{{Beat: 0, Bpm: bpm}}, changes...)
+	}
+	for _, c := range changes {
+		if !(c.Bpm > 0) || math.IsInf(c.Bpm, 0) {
+			return nil, fmt.Errorf("tempo at beat %g has invalid bpm %g", c.Beat, c.Bpm)
+		}
+	}
+	for _, sig := range signatures {
+		if sig.Numerator <= 0 || sig.Denominator <= 0 {
+			return nil, fmt.Errorf("time signature at beat %g has no beats: %d/%d", sig.Beat, sig.Numerator, sig.Denominator)
+		}
+	}
+
+	m := &TempoMap{}
+	for i, c := range changes {
+		seg := tempoSegment{beat: c.Beat, bpm: c.Bpm}
+		if c.Ramp && i+1 < len(changes) && changes[i+1].Beat > c.Beat {
+			next := changes[i+1]
+			seg.slope = (next.Bpm - c.Bpm) / (next.Beat - c.Beat)
+		}
+		if i > 0 {
+			prev := m.segments[i-1]
+			seg.seconds = prev.secondsAt(seg.beat)
+		}
+		m.segments = append(m.segments, seg)
+	}
+
+	sort.SliceStable(signatures, func(i, j int) bool { return signatures[i].Beat < signatures[j].Beat })
+	if len(signatures) == 0 || signatures[0].Beat > 0 {
+		signatures = append([]TimeSignature{{Beat: 0, Numerator: 4, Denominator: 4}}, signatures...)
+	}
+	for i, sig := range signatures {
+		start := measureStart{signature: sig}
+		if i > 0 {
+			prev := m.measures[i-1]
+			beats := sig.Beat - prev.signature.Beat
+			start.measure = prev.measure + int(math.Ceil(beats/prev.signature.BeatsPerMeasure()))
+		}
+		m.measures = append(m.measures, start)
+	}
+
+	return m, nil
+}
+
+// SecondsAt returns the audio time of a beat.
+func (m *TempoMap) SecondsAt(beat float64) float64 {
+	return m.segmentAtBeat(beat).secondsAt(beat)
+}
+
+// BeatAt returns the beat playing at an audio time.
+func (m *TempoMap) BeatAt(seconds float64) float64 {
+	i := sort.Search(len(m.segments), func(i int) bool { return m.segments[i].seconds > seconds }) - 1
+	if i < 0 {
+		i = 0
+	}
+	return m.segments[i].beatAt(seconds)
+}
+
+// BpmAt returns the tempo at a beat.
+func (m *TempoMap) BpmAt(beat float64) float64 {
+	seg := m.segmentAtBeat(beat)
+	return seg.bpm + seg.slope*math.Max(0, beat-seg.beat)
+}
+
+// SignatureAt returns the time signature in effect at a beat.
+func (m *TempoMap) SignatureAt(beat float64) TimeSignature {
+	return m.measureStartAt(beat).signature
+}
+
+// MeasureAt returns the measure index of a beat and the beat position inside
+// that measure.
+func (m *TempoMap) MeasureAt(beat float64) (int, float64) {
+	start := m.measureStartAt(beat)
+	length := start.signature.BeatsPerMeasure()
+	elapsed := beat - start.signature.Beat
+	measures := math.Floor(elapsed / length)
+	return start.measure + int(measures), elapsed - measures*length
+}
+
+func (m *TempoMap) segmentAtBeat(beat float64) tempoSegment {
+	i := sort.Search(len(m.segments), func(i int) bool { return m.segments[i].beat > beat }) - 1
+	if i < 0 {
+		i = 0
+	}
+	return m.segments[i]
+}
+
+func (m *TempoMap) measureStartAt(beat float64) measureStart {
+	i := sort.Search(len(m.measures), func(i int) bool { return m.measures[i].signature.Beat > beat }) - 1
+	if i < 0 {
+		i = 0
+	}
+	return m.measures[i]
+}
+
+// With a tempo that changes linearly by slope per beat, dt/db = 60 / bpm(b),
+// which integrates to a logarithm.
+func (s tempoSegment) secondsAt(beat float64) float64 {
+	db := beat - s.beat
+	if s.slope == 0 || db < 0 {
+		return s.seconds + db*60/s.bpm
+	}
+	return s.seconds + 60/s.slope*math.Log((s.bpm+s.slope*db)/s.bpm)
+}
+
+func (s tempoSegment) beatAt(seconds float64) float64 {
+	dt := seconds - s.seconds
+	if s.slope == 0 || dt < 0 {
+		return s.beat + dt*s.bpm/60
+	}
+	return s.beat + s.bpm*(math.Exp(s.slope*dt/60)-1)/s.slope
+}
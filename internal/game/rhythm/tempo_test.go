@@ -0,0 +1,167 @@
+package gamerhythm
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func mustTempoMap(t *testing.T, bpm float64, data *TempoMapData) *TempoMap {
+	t.Helper()
+	m, err := NewTempoMap(bpm, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestTempoMapRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		bpm  float64
+		data *TempoMapData
+	}{
+		{
+			name: "constant tempo",
+			bpm:  120,
+		},
+		{
+			name: "tempo change",
+			bpm:  120,
+			data: &TempoMapData{Tempos: []TempoChange{
+				{Beat: 16, Bpm: 90},
+				{Beat: 32, Bpm: 180},
+			}},
+		},
+		{
+			name: "ritardando",
+			bpm:  140,
+			data: &TempoMapData{Tempos: []TempoChange{
+				{Beat: 8, Bpm: 140, Ramp: true},
+				{Beat: 16, Bpm: 70},
+			}},
+		},
+		{
+			name: "accelerando from the start",
+			bpm:  100,
+			data: &TempoMapData{Tempos: []TempoChange{
+				{Beat: 0, Bpm: 60, Ramp: true},
+				{Beat: 12, Bpm: 200},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mustTempoMap(t, tt.bpm, tt.data)
+			for beat := -2.0; beat <= 64; beat += 0.25 {
+				got := m.BeatAt(m.SecondsAt(beat))
+				if math.Abs(got-beat) > epsilon {
+					t.Fatalf("BeatAt(SecondsAt(%v)) = %v", beat, got)
+				}
+			}
+			for seconds := -1.0; seconds <= 30; seconds += 0.1 {
+				got := m.SecondsAt(m.BeatAt(seconds))
+				if math.Abs(got-seconds) > epsilon {
+					t.Fatalf("SecondsAt(BeatAt(%v)) = %v", seconds, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTempoMapSecondsAt(t *testing.T) {
+	m := mustTempoMap(t, 120, &TempoMapData{Tempos: []TempoChange{
+		{Beat: 8, Bpm: 60},
+	}})
+
+	tests := []struct {
+		beat    float64
+		seconds float64
+	}{
+		{beat: 0, seconds: 0},
+		{beat: 4, seconds: 2},
+		{beat: 8, seconds: 4},
+		{beat: 10, seconds: 6},
+	}
+
+	for _, tt := range tests {
+		if got := m.SecondsAt(tt.beat); math.Abs(got-tt.seconds) > epsilon {
+			t.Errorf("SecondsAt(%v) = %v, want %v", tt.beat, got, tt.seconds)
+		}
+	}
+}
+
+func TestTempoMapRampIsContinuous(t *testing.T) {
+	m := mustTempoMap(t, 120, &TempoMapData{Tempos: []TempoChange{
+		{Beat: 4, Bpm: 120, Ramp: true},
+		{Beat: 8, Bpm: 60},
+	}})
+
+	if got := m.BpmAt(6); math.Abs(got-90) > epsilon {
+		t.Errorf("BpmAt(6) = %v, want 90", got)
+	}
+
+	// A slowing tempo takes longer than the start tempo and less than the end one.
+	elapsed := m.SecondsAt(8) - m.SecondsAt(4)
+	if elapsed <= 2 || elapsed >= 4 {
+		t.Errorf("ramp lasted %v seconds, want between 2 and 4", elapsed)
+	}
+}
+
+func TestTempoMapMeasureAt(t *testing.T) {
+	m := mustTempoMap(t, 120, &TempoMapData{TimeSignatures: []TimeSignature{
+		{Beat: 8, Numerator: 3, Denominator: 4},
+		{Beat: 14, Numerator: 6, Denominator: 8},
+	}})
+
+	tests := []struct {
+		beat    float64
+		measure int
+		inner   float64
+	}{
+		{beat: 0, measure: 0, inner: 0},
+		{beat: 5, measure: 1, inner: 1},
+		{beat: 8, measure: 2, inner: 0},
+		{beat: 11.5, measure: 3, inner: 0.5},
+		{beat: 14, measure: 4, inner: 0},
+		{beat: 17, measure: 5, inner: 0},
+	}
+
+	for _, tt := range tests {
+		measure, inner := m.MeasureAt(tt.beat)
+		if measure != tt.measure || math.Abs(inner-tt.inner) > epsilon {
+			t.Errorf("MeasureAt(%v) = (%v, %v), want (%v, %v)", tt.beat, measure, inner, tt.measure, tt.inner)
+		}
+	}
+}
+
+func TestTempoMapRejectsInvalidData(t *testing.T) {
+	tests := []struct {
+		name string
+		bpm  float64
+		data *TempoMapData
+	}{
+		{name: "zero bpm", bpm: 0},
+		{name: "negative bpm", bpm: -120},
+		{name: "zero bpm change", bpm: 120, data: &TempoMapData{Tempos: []TempoChange{{Beat: 8, Bpm: 0}}}},
+		{name: "ramp to a negative bpm", bpm: 120, data: &TempoMapData{Tempos: []TempoChange{
+			{Beat: 4, Bpm: 120, Ramp: true},
+			{Beat: 8, Bpm: -60},
+		}}},
+		{name: "zero numerator", bpm: 120, data: &TempoMapData{TimeSignatures: []TimeSignature{{Beat: 0, Numerator: 0, Denominator: 4}}}},
+		{name: "zero denominator", bpm: 120, data: &TempoMapData{TimeSignatures: []TimeSignature{{Beat: 4, Numerator: 3, Denominator: 0}}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewTempoMap(tt.bpm, tt.data); err == nil {
+			t.Errorf("%s: built a tempo map, want an error", tt.name)
+		}
+	}
+
+	// The song bpm is not used when the tempo map starts at beat 0.
+	if _, err := NewTempoMap(0, &TempoMapData{Tempos: []TempoChange{{Beat: 0, Bpm: 90}}}); err != nil {
+		t.Errorf("tempo map starting at beat 0: %v", err)
+	}
+}
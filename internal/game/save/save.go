@@ -0,0 +1,193 @@
+package gamesave
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+)
+
+const (
+	// FileName is the name of the save file in the save manager.
+	FileName = "save.json"
+
+	// SchemaVersion is the version written in new save files. Bump it and add a
+	// migration whenever the layout of Data changes.
+	SchemaVersion = 2
+)
+
+// ErrNewerVersion is returned when the save file was written by a newer game.
+var ErrNewerVersion = errors.New("save file has a newer schema version")
+
+// Storage reads and writes save files.
+type Storage interface {
+	Load(name string) ([]byte, error)
+	Save(name string, data []byte) error
+}
+
+// Stats are the statistics kept for one chart of a song.
+type Stats struct {
+	BestScore int `json:"best_score"`
+	MaxCombo  int `json:"max_combo"`
+	// Judgements are the grade counts of the best scoring play.
+	Judgements map[string]int `json:"judgements"`
+	Plays      int            `json:"plays"`
+	// Cleared is set once the chart was played to the end, without failing,
+	// with a passing accuracy.
+	Cleared bool `json:"cleared"`
+}
+
+// Run is the outcome of a single play of a chart.
+type Run struct {
+	Score      int
+	MaxCombo   int
+	Judgements map[string]int
+	Cleared    bool
+}
+
+// Data is the whole content of the save file.
+type Data struct {
+	Version int               `json:"version"`
+	Charts  map[string]*Stats `json:"charts"`
+}
+
+func New() *Data {
+	return &Data{
+		Version: SchemaVersion,
+		Charts:  make(map[string]*Stats),
+	}
+}
+
+// migrations[v] upgrades raw save data from version v to version v+1.
+var migrations = []func(raw map[string]json.RawMessage) error{
+	// Version 0 files were written before the version field existed. Their
+	// charts already have the version 1 layout.
+	func(raw map[string]json.RawMessage) error {
+		if _, ok := raw["charts"]; !ok {
+			raw["charts"] = json.RawMessage("{}")
+		}
+		return nil
+	},
+	// Version 1 set the clear flag on every chart played to the end, however
+	// badly. Keep it only where the judgements of the best play pass.
+	func(raw map[string]json.RawMessage) error {
+		var charts map[string]map[string]json.RawMessage
+		if err := json.Unmarshal(raw["charts"], &charts); err != nil {
+			return err
+		}
+		for key, chart := range charts {
+			var judgements map[string]int
+			if j, ok := chart["judgements"]; ok {
+				if err := json.Unmarshal(j, &judgements); err != nil {
+					return fmt.Errorf("chart %s: %w", key, err)
+				}
+			}
+			tally := gamerhythm.TallyFromCounts(judgements)
+			if !tally.Passed() {
+				chart["cleared"] = json.RawMessage("false")
+			}
+		}
+		data, err := json.Marshal(charts)
+		if err != nil {
+			return err
+		}
+		raw["charts"] = data
+		return nil
+	},
+}
+
+// Load reads the save file. A missing file returns empty save data.
+func Load(storage Storage) (*Data, error) {
+	raw, err := storage.Load(FileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Decode(raw)
+}
+
+// Decode parses save data, migrating it from older schema versions.
+func Decode(data []byte) (*Data, error) {
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	version := 0
+	if v, ok := raw["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, fmt.Errorf("invalid save version: %w", err)
+		}
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("%w: %d", ErrNewerVersion, version)
+	}
+
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](raw); err != nil {
+			return nil, fmt.Errorf("failed to migrate save from version %d: %w", version, err)
+		}
+	}
+
+	raw["version"] = json.RawMessage(fmt.Sprint(SchemaVersion))
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	d := New()
+	if err := json.Unmarshal(migrated, d); err != nil {
+		return nil, err
+	}
+	if d.Charts == nil {
+		d.Charts = make(map[string]*Stats)
+	}
+	return d, nil
+}
+
+// Save writes the save file.
+func (d *Data) Save(storage Storage) error {
+	d.Version = SchemaVersion
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.Save(FileName, data)
+}
+
+// ChartKey identifies a chart of a song in the save file.
+func ChartKey(songID, difficulty string) string {
+	return songID + "/" + difficulty
+}
+
+// Chart returns the statistics of a chart, if it was ever played.
+func (d *Data) Chart(songID, difficulty string) (*Stats, bool) {
+	stats, ok := d.Charts[ChartKey(songID, difficulty)]
+	return stats, ok
+}
+
+// Record adds a play to the statistics of a chart and reports whether it set a
+// new best score.
+func (d *Data) Record(songID, difficulty string, run Run) bool {
+	key := ChartKey(songID, difficulty)
+	stats, ok := d.Charts[key]
+	if !ok {
+		stats = &Stats{}
+		d.Charts[key] = stats
+	}
+
+	stats.Plays++
+	stats.MaxCombo = max(stats.MaxCombo, run.MaxCombo)
+	stats.Cleared = stats.Cleared || run.Cleared
+
+	isBest := !ok || run.Score > stats.BestScore
+	if isBest {
+		stats.BestScore = run.Score
+		stats.Judgements = run.Judgements
+	}
+	return isBest
+}
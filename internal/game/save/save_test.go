@@ -0,0 +1,144 @@
+package gamesave
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leandroatallah/drummer/internal/engine/systems/savemanager"
+)
+
+func TestDecodeMigratesVersion0(t *testing.T) {
+	tests := map[string]string{
+		"empty":       `{}`,
+		"with charts": `{"charts": {"song/normal": {"best_score": 120, "plays": 3}}}`,
+	}
+	for name, data := range tests {
+		d, err := Decode([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if d.Version != SchemaVersion {
+			t.Errorf("%s: version = %d, want %d", name, d.Version, SchemaVersion)
+		}
+		if d.Charts == nil {
+			t.Errorf("%s: charts is nil", name)
+		}
+	}
+
+	d, _ := Decode([]byte(tests["with charts"]))
+	if stats, ok := d.Chart("song", "normal"); !ok || stats.BestScore != 120 || stats.Plays != 3 {
+		t.Errorf("chart = %+v, %v; want the version 0 stats", stats, ok)
+	}
+}
+
+func TestDecodeMigratesVersion1ClearFlags(t *testing.T) {
+	data := `{
+		"version": 1,
+		"charts": {
+			"passed/normal": {"best_score": 90, "cleared": true, "judgements": {"Perfect": 9, "Miss": 1}},
+			"failed/normal": {"best_score": 10, "cleared": true, "judgements": {"Perfect": 1, "Miss": 9}},
+			"unplayed/hard": {"best_score": 0, "cleared": true},
+			"not-cleared/easy": {"best_score": 50, "cleared": false, "judgements": {"Perfect": 10}}
+		}
+	}`
+	d, err := Decode([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		"passed/normal":    true,
+		"failed/normal":    false,
+		"unplayed/hard":    false,
+		"not-cleared/easy": false,
+	}
+	for key, cleared := range want {
+		stats, ok := d.Charts[key]
+		if !ok {
+			t.Errorf("%s: missing", key)
+			continue
+		}
+		if stats.Cleared != cleared {
+			t.Errorf("%s: cleared = %v, want %v", key, stats.Cleared, cleared)
+		}
+	}
+	if got := d.Charts["passed/normal"].Judgements["Perfect"]; got != 9 {
+		t.Errorf("judgements were not kept, Perfect = %d", got)
+	}
+}
+
+func TestDecodeRejectsNewerVersion(t *testing.T) {
+	_, err := Decode([]byte(`{"version": 99, "charts": {}}`))
+	if !errors.Is(err, ErrNewerVersion) {
+		t.Errorf("err = %v, want ErrNewerVersion", err)
+	}
+
+	if _, err := Decode([]byte(`{"version": "one"}`)); err == nil {
+		t.Error("decoded an invalid version")
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	storage := savemanager.MemoryStore{}
+
+	d, err := Load(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Charts) != 0 {
+		t.Fatalf("a missing file loaded %d charts", len(d.Charts))
+	}
+
+	d.Record("song", "hard", Run{Score: 100, MaxCombo: 12, Judgements: map[string]int{"Perfect": 12}, Cleared: true})
+	if err := d.Save(storage); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, ok := loaded.Chart("song", "hard")
+	if !ok || stats.BestScore != 100 || stats.MaxCombo != 12 || !stats.Cleared || stats.Plays != 1 {
+		t.Errorf("loaded %+v, %v", stats, ok)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	d := New()
+
+	if !d.Record("song", "normal", Run{Score: 50, MaxCombo: 20, Cleared: true}) {
+		t.Error("the first play is not a best score")
+	}
+	if d.Record("song", "normal", Run{Score: 40, MaxCombo: 30}) {
+		t.Error("a lower score is a best score")
+	}
+	if !d.Record("song", "normal", Run{Score: 60, MaxCombo: 5}) {
+		t.Error("a higher score is not a best score")
+	}
+
+	stats, _ := d.Chart("song", "normal")
+	if stats.Plays != 3 || stats.BestScore != 60 || stats.MaxCombo != 30 || !stats.Cleared {
+		t.Errorf("stats = %+v", stats)
+	}
+	if _, ok := d.Chart("song", "hard"); ok {
+		t.Error("difficulties are not kept apart")
+	}
+}
+
+func TestSettingsOverrides(t *testing.T) {
+	s := DefaultSettings()
+	s.VisualOffsetMs = 20
+
+	o := s.Overrides()
+	if o.AudioOffset == nil || *o.AudioOffset != DefaultAudioOffsetMs*time.Millisecond {
+		t.Errorf("AudioOffset = %v, want %dms", o.AudioOffset, DefaultAudioOffsetMs)
+	}
+	if o.VisualOffset == nil || *o.VisualOffset != 20*time.Millisecond {
+		t.Errorf("VisualOffset = %v, want 20ms", o.VisualOffset)
+	}
+	if o.MusicVolume != nil || o.Fullscreen != nil {
+		t.Error("options that were never set override the defaults")
+	}
+}
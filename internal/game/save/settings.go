@@ -0,0 +1,105 @@
+package gamesave
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/leandroatallah/drummer/internal/config"
+)
+
+const (
+	// SettingsFileName is the name of the settings file in the save manager.
+	SettingsFileName = "settings.json"
+
+	// SettingsVersion is the version written in new settings files.
+	SettingsVersion = 1
+
+	// DefaultAudioOffsetMs matches the note offset of 0.23 beats that used to be
+	// tuned by hand for a 120 BPM song.
+	DefaultAudioOffsetMs = -115
+)
+
+// Settings are the player options kept between sessions.
+type Settings struct {
+	Version int `json:"version"`
+	// AudioOffsetMs is how late the player hears the music. Judgement uses the
+	// song position minus this offset.
+	AudioOffsetMs int `json:"audio_offset_ms"`
+	// VisualOffsetMs is how late the player sees a frame. Notes are drawn ahead
+	// by this offset.
+	VisualOffsetMs int `json:"visual_offset_ms"`
+	// FailMode makes the thermometer a life gauge that ends the song when it
+	// runs out. It is off by default: songs play to the end however badly they
+	// go.
+	FailMode bool `json:"fail_mode"`
+	// Bindings are the inputs chosen for each action, by action name. Actions
+	// that are missing keep their default bindings.
+	Bindings map[string][]string `json:"bindings,omitempty"`
+	// Theme is the ID of the color theme. Empty is the default theme.
+	Theme string `json:"theme,omitempty"`
+	// The options below override the config defaults once they are set.
+	MusicVolume *float64 `json:"music_volume,omitempty"`
+	SFXVolume   *float64 `json:"sfx_volume,omitempty"`
+	ScrollSpeed *float64 `json:"scroll_speed,omitempty"`
+	WindowScale *int     `json:"window_scale,omitempty"`
+	Fullscreen  *bool    `json:"fullscreen,omitempty"`
+}
+
+func DefaultSettings() *Settings {
+	return &Settings{
+		Version:       SettingsVersion,
+		AudioOffsetMs: DefaultAudioOffsetMs,
+	}
+}
+
+// LoadSettings reads the settings file. Missing values keep their defaults.
+func LoadSettings(storage Storage) (*Settings, error) {
+	settings := DefaultSettings()
+
+	raw, err := storage.Load(SettingsFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+
+	if err := json.Unmarshal(raw, settings); err != nil {
+		return DefaultSettings(), err
+	}
+	settings.Version = SettingsVersion
+	return settings, nil
+}
+
+// Save writes the settings file.
+func (s *Settings) Save(storage Storage) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.Save(SettingsFileName, data)
+}
+
+// Overrides are the options of the settings that replace config defaults.
+func (s *Settings) Overrides() config.Overrides {
+	audioOffset, visualOffset := s.AudioOffset(), s.VisualOffset()
+	return config.Overrides{
+		MusicVolume:  s.MusicVolume,
+		SFXVolume:    s.SFXVolume,
+		ScrollSpeed:  s.ScrollSpeed,
+		WindowScale:  s.WindowScale,
+		Fullscreen:   s.Fullscreen,
+		AudioOffset:  &audioOffset,
+		VisualOffset: &visualOffset,
+	}
+}
+
+func (s *Settings) AudioOffset() time.Duration {
+	return time.Duration(s.AudioOffsetMs) * time.Millisecond
+}
+
+func (s *Settings) VisualOffset() time.Duration {
+	return time.Duration(s.VisualOffsetMs) * time.Millisecond
+}
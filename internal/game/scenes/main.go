@@ -1,8 +1,20 @@
 package gamescene
 
 import (
+	"image"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/leandroatallah/drummer/internal/config"
 	"github.com/leandroatallah/drummer/internal/engine/contracts/navigation"
 	"github.com/leandroatallah/drummer/internal/engine/core"
+	"github.com/leandroatallah/drummer/internal/engine/core/game/state"
+	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gamereplay "github.com/leandroatallah/drummer/internal/game/replay"
+	gamesave "github.com/leandroatallah/drummer/internal/game/save"
+	gamesongs "github.com/leandroatallah/drummer/internal/game/songs"
+	gametheme "github.com/leandroatallah/drummer/internal/game/theme"
 )
 
 const (
@@ -11,9 +23,82 @@ const (
 	ScenePlay
 	SceneTrackSelection
 	SceneThanks
+	SceneResults
+	SceneCalibration
+	SceneEditor
+	SceneGameOver
+	SceneControls
+	SceneSettings
 )
 
-func InitSceneMap(context *core.AppContext) navigation.SceneMap {
+// Selection keeps the choices made in menus, and the result of the last song,
+// for the scenes that follow.
+type Selection struct {
+	Song       *gamesongs.Entry
+	Difficulty string
+	Result     *Result
+	// Replay makes the next play scene watch a replay instead of reading input.
+	Replay *gamereplay.Replay
+	// Practice makes the next play scene start in practice mode.
+	Practice bool
+}
+
+// setGameState switches the game state, when the game manages states.
+func setGameState(context *core.AppContext, stateID state.GameStateEnum) {
+	if context.StateManager == nil {
+		return
+	}
+	if err := context.StateManager.SetState(stateID); err != nil {
+		log.Printf("failed to set game state: %v", err)
+	}
+}
+
+// uiSound ticks when a menu cursor moves or an option is chosen. It plays on
+// the UI bus, which lowers the music under it.
+const uiSound = "assets/audio/jab8.ogg"
+
+func playUISound(context *core.AppContext) {
+	context.AudioManager.PlaySound(uiSound)
+}
+
+// tapAnywhere makes a tap on any part of the screen trigger an action.
+func tapAnywhere(context *core.AppContext, action input.Action) {
+	cfg := config.Get()
+	context.InputManager.SetZones([]input.Zone{
+		{Rect: image.Rect(0, 0, cfg.ScreenWidth, cfg.ScreenHeight), Action: action},
+	})
+}
+
+// applySettings makes the settings take effect: the config overrides, with the
+// note offsets, the volumes, the window and the theme.
+func applySettings(context *core.AppContext, settings *gamesave.Settings, themes *gametheme.Registry) {
+	config.SetOverrides(settings.Overrides())
+	cfg := config.Get()
+
+	context.AudioManager.SetBusGain(audiomanager.BusMusic, cfg.MusicVolume)
+	context.AudioManager.SetBusGain(audiomanager.BusSFX, cfg.SFXVolume)
+	context.AudioManager.SetBusGain(audiomanager.BusUI, cfg.SFXVolume)
+	ebiten.SetWindowSize(cfg.ScreenWidth*cfg.WindowScale, cfg.ScreenHeight*cfg.WindowScale)
+	ebiten.SetFullscreen(cfg.Fullscreen)
+	if !themes.Set(settings.Theme) && settings.Theme != "" {
+		log.Printf("unknown theme %q", settings.Theme)
+	}
+}
+
+func InitSceneMap(context *core.AppContext, songs *gamesongs.Registry, themes *gametheme.Registry) navigation.SceneMap {
+	selection := &Selection{Difficulty: gamesongs.DifficultyNormal}
+	records := NewRecords(context.SaveManager)
+	settings, err := gamesave.LoadSettings(context.SaveManager)
+	if err != nil {
+		log.Printf("failed to load settings: %v", err)
+	}
+	bindings, err := input.DecodeBindings(settings.Bindings)
+	if err != nil {
+		log.Printf("failed to load bindings: %v", err)
+	}
+	context.InputManager.SetBindings(bindings)
+	applySettings(context, settings, themes)
+
 	sceneMap := navigation.SceneMap{
 		SceneIntro: func() navigation.Scene {
 			return NewIntroScene(context)
@@ -22,14 +107,42 @@ func InitSceneMap(context *core.AppContext) navigation.SceneMap {
 			return NewMenuScene(context)
 		},
 		ScenePlay: func() navigation.Scene {
-			return NewPlayScene(context)
+			play, err := NewPlayScene(context, selection, records, settings)
+			if err != nil {
+				log.Printf("failed to load song: %v", err)
+				return NewTrackSelectionScene(context, songs, selection, records, settings)
+			}
+			return play
 		},
 		SceneTrackSelection: func() navigation.Scene {
-			return NewTrackSelectionScene(context)
+			return NewTrackSelectionScene(context, songs, selection, records, settings)
 		},
 		SceneThanks: func() navigation.Scene {
 			return NewThanksScene(context)
 		},
+		SceneResults: func() navigation.Scene {
+			return NewResultsScene(context, selection, records)
+		},
+		SceneCalibration: func() navigation.Scene {
+			return NewCalibrationScene(context, settings)
+		},
+		SceneEditor: func() navigation.Scene {
+			editor, err := NewEditorScene(context, selection, records, settings, songs)
+			if err != nil {
+				log.Printf("failed to load song: %v", err)
+				return NewTrackSelectionScene(context, songs, selection, records, settings)
+			}
+			return editor
+		},
+		SceneGameOver: func() navigation.Scene {
+			return NewGameOverScene(context, selection)
+		},
+		SceneControls: func() navigation.Scene {
+			return NewControlsScene(context, settings)
+		},
+		SceneSettings: func() navigation.Scene {
+			return NewSettingsScene(context, settings, themes)
+		},
 	}
 	return sceneMap
 }
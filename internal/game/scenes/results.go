@@ -0,0 +1,84 @@
+package gamescene
+
+import (
+	"errors"
+	"log"
+
+	gamereplay "github.com/leandroatallah/drummer/internal/game/replay"
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+	gamesave "github.com/leandroatallah/drummer/internal/game/save"
+)
+
+// Result is the outcome of playing one chart of a song.
+type Result struct {
+	SongID     string
+	Title      string
+	Difficulty string
+	Score      int
+	MaxStreak  int
+	Tally      gamerhythm.Tally
+	Cleared    bool
+	NewBest    bool
+	// Failed is set when the life gauge ran out, Progress far into the song.
+	Failed   bool
+	Progress float64
+	// Thermometer holds the thermometer value at every beat of the song.
+	Thermometer []int
+	// Replay is the input of the session. Watched is set when the result comes
+	// from watching it rather than playing.
+	Replay  *gamereplay.Replay
+	Watched bool
+	// Practice is set when the song was played in practice mode.
+	Practice bool
+}
+
+// Judgements returns the grade counts of the result, keyed by grade name.
+func (r Result) Judgements() map[string]int {
+	return r.Tally.Counts()
+}
+
+// Records keeps the statistics of each song chart in the save file.
+// Difficulties of the same song are kept apart.
+type Records struct {
+	data     *gamesave.Data
+	storage  gamesave.Storage
+	readOnly bool
+}
+
+func NewRecords(storage gamesave.Storage) *Records {
+	r := &Records{storage: storage}
+
+	data, err := gamesave.Load(storage)
+	if err != nil {
+		log.Printf("failed to load save data: %v", err)
+		data = gamesave.New()
+		// Don't overwrite a save file this version can't read.
+		r.readOnly = errors.Is(err, gamesave.ErrNewerVersion)
+	}
+	r.data = data
+
+	return r
+}
+
+func (r *Records) Best(songID, difficulty string) (*gamesave.Stats, bool) {
+	return r.data.Chart(songID, difficulty)
+}
+
+// Submit stores a result and reports whether it is a new best score.
+func (r *Records) Submit(result Result) bool {
+	isBest := r.data.Record(result.SongID, result.Difficulty, gamesave.Run{
+		Score:      result.Score,
+		MaxCombo:   result.MaxStreak,
+		Judgements: result.Judgements(),
+		Cleared:    result.Cleared,
+	})
+
+	if r.readOnly {
+		return isBest
+	}
+	if err := r.data.Save(r.storage); err != nil {
+		log.Printf("failed to save records: %v", err)
+	}
+
+	return isBest
+}
@@ -0,0 +1,189 @@
+package gamescene
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/core"
+	"github.com/leandroatallah/drummer/internal/engine/core/scene"
+	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+	gamesave "github.com/leandroatallah/drummer/internal/game/save"
+)
+
+const (
+	calibrationSound = "assets/audio/jab8.wav"
+	calibrationBpm   = 100
+	// calibrationLeadIn is the number of ticks played before taps are counted.
+	calibrationLeadIn = 4
+	calibrationTaps   = 8
+	calibrationFlash  = 100 * time.Millisecond
+	// calibrationFlashSize is the side of the flashing square, in pixels.
+	calibrationFlashSize = 32
+	calibrationMargin    = 4
+)
+
+type calibrationPhase int
+
+const (
+	calibrateAudio calibrationPhase = iota
+	calibrateVisual
+	calibrationDone
+)
+
+// CalibrationScene measures the audio and visual latency of the player setup.
+// First the player taps along a metronome click, then along a silent flash.
+type CalibrationScene struct {
+	scene.BaseScene
+
+	settings     *gamesave.Settings
+	phase        calibrationPhase
+	start        time.Time
+	ticks        int
+	taps         []time.Duration
+	audioOffset  time.Duration
+	visualOffset time.Duration
+}
+
+func NewCalibrationScene(context *core.AppContext, settings *gamesave.Settings) *CalibrationScene {
+	scene := CalibrationScene{settings: settings}
+	scene.SetAppContext(context)
+	return &scene
+}
+
+func (s *CalibrationScene) OnStart() {
+	s.AudioManager().PauseAll()
+	s.startPhase(calibrateAudio)
+	s.EnableKeys()
+}
+
+func (s *CalibrationScene) Update() error {
+	if s.IsKeysDisabled {
+		return nil
+	}
+
+	if s.Input().IsJustPressed(input.ActionBack) {
+		s.DisableKeys()
+		s.Manager.NavigateTo(SceneMenu, transition.NewFader(), true)
+		return nil
+	}
+
+	if s.phase == calibrationDone {
+		if s.Input().IsJustPressed(input.ActionConfirm) {
+			s.save()
+			s.DisableKeys()
+			s.Manager.NavigateTo(SceneMenu, transition.NewFader(), true)
+		}
+		return nil
+	}
+
+	elapsed := time.Since(s.start)
+	if elapsed < 0 {
+		return nil
+	}
+
+	// Play the click of every tick that is due.
+	for s.ticks <= int(elapsed/s.interval()) {
+		if s.phase == calibrateAudio {
+			s.AudioManager().PlaySound(calibrationSound)
+		}
+		s.ticks++
+	}
+
+	if s.isTapPressed() && elapsed > s.interval()*(calibrationLeadIn-1)+s.interval()/2 {
+		s.taps = append(s.taps, elapsed)
+	}
+
+	if len(s.taps) >= calibrationTaps {
+		offset, _ := gamerhythm.MeasureOffset(s.taps, s.interval(), calibrationTaps)
+		if s.phase == calibrateAudio {
+			s.audioOffset = offset
+			s.startPhase(calibrateVisual)
+		} else {
+			s.visualOffset = offset
+			s.phase = calibrationDone
+		}
+	}
+
+	return nil
+}
+
+func (s *CalibrationScene) Draw(screen *ebiten.Image) {
+	cfg := config.Get()
+	screen.Fill(cfg.Colors.Dark)
+
+	var lines []string
+	switch s.phase {
+	case calibrateAudio:
+		lines = []string{"AUDIO SYNC", "Tap SPACE on", "each click.", s.progress()}
+	case calibrateVisual:
+		lines = []string{"VIDEO SYNC", "Tap SPACE on", "each flash.", s.progress()}
+	case calibrationDone:
+		lines = []string{
+			"DONE",
+			fmt.Sprintf("AUDIO %+dms", s.audioOffset.Milliseconds()),
+			fmt.Sprintf("VIDEO %+dms", s.visualOffset.Milliseconds()),
+			"ENTER: save",
+		}
+	}
+	for i, line := range lines {
+		DrawText(screen, line, calibrationMargin, float64(calibrationMargin+i*uiLineHeight), cfg.Colors.Light)
+	}
+
+	if s.phase == calibrateVisual {
+		elapsed := time.Since(s.start)
+		if elapsed >= 0 && elapsed%s.interval() < calibrationFlash {
+			x := float32(cfg.ScreenWidth/2 - calibrationFlashSize/2)
+			y := float32(cfg.ScreenHeight - calibrationFlashSize - calibrationMargin*4)
+			vector.DrawFilledRect(screen, x, y, calibrationFlashSize, calibrationFlashSize, cfg.Colors.Light, false)
+		}
+	}
+}
+
+func (s *CalibrationScene) OnFinish() {}
+
+func (s *CalibrationScene) startPhase(phase calibrationPhase) {
+	s.phase = phase
+	// Leave one beat of silence between phases.
+	s.start = time.Now().Add(s.interval())
+	s.ticks = 0
+	s.taps = nil
+}
+
+func (s *CalibrationScene) interval() time.Duration {
+	return time.Minute / calibrationBpm
+}
+
+func (s *CalibrationScene) progress() string {
+	elapsed := time.Since(s.start)
+	if elapsed < s.interval()*calibrationLeadIn {
+		return "Get ready..."
+	}
+	return fmt.Sprintf("%d/%d", len(s.taps), calibrationTaps)
+}
+
+func (s *CalibrationScene) isTapPressed() bool {
+	if s.Input().IsJustPressed(input.ActionBonus) {
+		return true
+	}
+	for _, action := range input.LaneActions {
+		if s.Input().IsJustPressed(action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *CalibrationScene) save() {
+	s.settings.AudioOffsetMs = int(s.audioOffset.Milliseconds())
+	s.settings.VisualOffsetMs = int(s.visualOffset.Milliseconds())
+	config.SetOverrides(s.settings.Overrides())
+	if err := s.settings.Save(s.AppContext.SaveManager); err != nil {
+		log.Printf("failed to save calibration: %v", err)
+	}
+}
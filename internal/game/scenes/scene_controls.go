@@ -0,0 +1,181 @@
+package gamescene
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/core"
+	"github.com/leandroatallah/drummer/internal/engine/core/scene"
+	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gamesave "github.com/leandroatallah/drummer/internal/game/save"
+)
+
+const (
+	controlsMargin = 4
+	// controlsListenTimeout is how long the scene waits for an input to bind
+	// before it gives up.
+	controlsListenTimeout = 5 * time.Second
+	controlsResetLabel    = "RESET DEFAULTS"
+)
+
+// ControlsScene lists the actions with their keyboard and gamepad bindings,
+// and lets the player bind a new input to any of them.
+//
+// Controls:
+//   - Up and Down: choose an action
+//   - Confirm: wait for the input to bind to the action
+//   - Back: save and return to the settings
+type ControlsScene struct {
+	scene.BaseScene
+
+	settings    *gamesave.Settings
+	cursor      int
+	scroll      int
+	listening   bool
+	listenStart time.Time
+	// refused is the reason the last rebind was refused, shown until the next
+	// one.
+	refused string
+}
+
+func NewControlsScene(context *core.AppContext, settings *gamesave.Settings) *ControlsScene {
+	scene := ControlsScene{settings: settings}
+	scene.SetAppContext(context)
+	return &scene
+}
+
+func (s *ControlsScene) OnStart() {
+	s.EnableKeys()
+}
+
+func (s *ControlsScene) Update() error {
+	if s.IsKeysDisabled {
+		return nil
+	}
+
+	if s.listening {
+		s.listen()
+		return nil
+	}
+
+	switch {
+	case s.Input().IsJustPressed(input.ActionBack):
+		s.save()
+		s.DisableKeys()
+		s.Manager.NavigateTo(SceneSettings, transition.NewFader(), true)
+	case s.Input().IsJustPressed(input.ActionUp):
+		s.moveCursor(s.cursor - 1)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionDown):
+		s.moveCursor(s.cursor + 1)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionConfirm):
+		playUISound(s.AppContext)
+		if s.cursor == len(input.Actions) {
+			s.Input().SetBindings(input.DefaultBindings())
+			return nil
+		}
+		s.listening = true
+		s.listenStart = time.Now()
+		s.refused = ""
+	}
+	return nil
+}
+
+// listen binds the next input pressed to the selected action.
+func (s *ControlsScene) listen() {
+	if time.Since(s.listenStart) > controlsListenTimeout {
+		s.listening = false
+		return
+	}
+
+	binding, ok := s.Input().JustPressedBinding()
+	if !ok {
+		return
+	}
+	if err := s.Input().Bindings().Rebind(input.Actions[s.cursor], binding); err != nil {
+		s.refused = err.Error()
+	}
+	s.listening = false
+}
+
+func (s *ControlsScene) save() {
+	s.settings.Bindings = s.Input().Bindings().Encode()
+	if err := s.settings.Save(s.AppContext.SaveManager); err != nil {
+		log.Printf("failed to save controls: %v", err)
+	}
+}
+
+func (s *ControlsScene) Draw(screen *ebiten.Image) {
+	cfg := config.Get()
+	screen.Fill(cfg.Colors.Dark)
+
+	DrawText(screen, fmt.Sprintf(" %-8s %-5s %s", "ACTION", "KEY", "PAD"), controlsMargin, controlsMargin, cfg.Colors.Medium)
+
+	for row := 0; row < s.visibleRows(); row++ {
+		i := s.scroll + row
+		if i > len(input.Actions) {
+			break
+		}
+
+		line := controlsResetLabel
+		if i < len(input.Actions) {
+			line = s.actionLine(input.Actions[i])
+		}
+		prefix := " "
+		if i == s.cursor {
+			prefix = ">"
+		}
+		DrawText(screen, prefix+line, controlsMargin, float64(controlsMargin+(row+1)*uiLineHeight), cfg.Colors.Light)
+	}
+
+	footer := "CONFIRM: rebind"
+	switch {
+	case s.listening:
+		footer = "Press an input..."
+	case s.refused != "":
+		footer = FitText(s.refused, cfg.ScreenWidth-controlsMargin*2)
+	}
+	DrawText(screen, footer, controlsMargin, float64(cfg.ScreenHeight-controlsMargin-uiLineHeight), cfg.Colors.Medium)
+}
+
+// actionLine shows the first keyboard and gamepad bindings of an action.
+func (s *ControlsScene) actionLine(action input.Action) string {
+	key, pad := "-", "-"
+	for _, b := range s.Input().Bindings()[action] {
+		switch {
+		case b.IsGamepad() && pad == "-":
+			pad = b.Label()
+		case !b.IsGamepad() && key == "-":
+			key = b.Label()
+		}
+	}
+	return fmt.Sprintf("%-8s %-5s %s", strings.ToUpper(action.String()), FitText(key, 5*uiCharWidth), pad)
+}
+
+func (s *ControlsScene) OnFinish() {}
+
+// visibleRows is the number of rows between the header and the footer.
+func (s *ControlsScene) visibleRows() int {
+	return (config.Get().ScreenHeight-controlsMargin*2)/uiLineHeight - 2
+}
+
+func (s *ControlsScene) moveCursor(cursor int) {
+	// The last row resets the bindings.
+	if cursor < 0 || cursor > len(input.Actions) {
+		return
+	}
+
+	s.cursor = cursor
+	if s.cursor < s.scroll {
+		s.scroll = s.cursor
+	}
+	if s.cursor >= s.scroll+s.visibleRows() {
+		s.scroll = s.cursor - s.visibleRows() + 1
+	}
+}
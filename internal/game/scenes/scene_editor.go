@@ -0,0 +1,373 @@
+package gamescene
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/core"
+	"github.com/leandroatallah/drummer/internal/engine/core/scene"
+	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gameplay "github.com/leandroatallah/drummer/internal/game/play"
+	gamesave "github.com/leandroatallah/drummer/internal/game/save"
+	gamesongs "github.com/leandroatallah/drummer/internal/game/songs"
+)
+
+const (
+	// editorMessageFrames is how long a status message stays on screen.
+	editorMessageFrames = 90
+	// noteEpsilon is the tolerance used to compare note onsets.
+	noteEpsilon = 1e-6
+)
+
+// editorSubdivisions are the grid steps per beat the cursor can snap to.
+var editorSubdivisions = []int{1, 2, 3, 4, 6, 8, 12, 16}
+
+// EditorScene edits the notes of a song chart on top of the play scene track.
+// The song files are embedded in the game, so saving keeps the edited song in
+// the save storage, where it overlays the embedded one (see
+// gamesongs.EditedChartName). Copy it over the file in assets/songs to ship it.
+//
+// Controls, with their default keys:
+//   - Play (Space): play or pause
+//   - Prev and Next ([ and ]): step the cursor back or forward by one grid step
+//   - Slower and Faster (- and =): change the grid subdivision
+//   - Lanes (arrow keys): toggle a note on the cursor (paused) or record it
+//     (recording)
+//   - Record (R): toggle record mode
+//   - Loop in, Loop out and No loop (Z, X and C): set the loop start, the loop
+//     end, or clear the loop
+//   - Save (S): save the chart
+//   - Back (Escape): back to track selection
+type EditorScene struct {
+	scene.BaseScene
+
+	play         *PlayScene
+	songs        *gamesongs.Registry
+	entry        *gamesongs.Entry
+	subdivision  int
+	recording    bool
+	loopStart    float64
+	loopEnd      float64
+	dirty        bool
+	message      string
+	messageTimer int
+	// status is the status column, redrawn every frame.
+	status *ebiten.Image
+}
+
+func NewEditorScene(context *core.AppContext, selection *Selection, records *Records, settings *gamesave.Settings, songs *gamesongs.Registry) (*EditorScene, error) {
+	play, err := NewPlayScene(context, selection, records, settings)
+	if err != nil {
+		return nil, err
+	}
+	play.SetAppContext(context)
+
+	scene := EditorScene{
+		play:        play,
+		songs:       songs,
+		entry:       selection.Song,
+		subdivision: 3,
+	}
+	scene.SetAppContext(context)
+	return &scene, nil
+}
+
+func (s *EditorScene) OnStart() {
+	s.play.OnStart()
+	s.status = DrawStatusRectangle(leftColumnWidth, s.play.ui.innerHeight)
+
+	s.AudioManager().PauseAll()
+	s.play.songPlayer = s.AudioManager().PlaySound(s.entry.AudioPath())
+	if s.play.songPlayer != nil {
+		s.play.songPlayer.Pause()
+	}
+
+	// Notes are only shown, never judged.
+	for _, n := range s.play.song.Notes {
+		n.Judged = true
+	}
+	s.play.song.SetPositionInBPM(0)
+
+	s.EnableKeys()
+}
+
+func (s *EditorScene) Update() error {
+	if s.IsKeysDisabled {
+		return nil
+	}
+
+	if s.messageTimer > 0 {
+		s.messageTimer--
+	}
+
+	song := s.play.song
+	player := s.play.songPlayer
+
+	switch {
+	case s.Input().IsJustPressed(input.ActionBack):
+		s.DisableKeys()
+		s.Manager.NavigateTo(SceneTrackSelection, transition.NewFader(), true)
+		return nil
+	case player == nil:
+		return nil
+	case s.Input().IsJustPressed(input.ActionPlayback):
+		if player.IsPlaying() {
+			player.Pause()
+			song.SetPositionInBPM(s.cursor())
+		} else {
+			player.Play()
+		}
+	case s.Input().IsJustPressed(input.ActionSave):
+		s.save()
+	case s.Input().IsJustPressed(input.ActionRecord):
+		s.recording = !s.recording
+	case s.Input().IsJustPressed(input.ActionSlower):
+		s.subdivision = max(s.subdivision-1, 0)
+	case s.Input().IsJustPressed(input.ActionFaster):
+		s.subdivision = min(s.subdivision+1, len(editorSubdivisions)-1)
+	case s.Input().IsJustPressed(input.ActionLoopStart):
+		s.loopStart = s.cursor()
+	case s.Input().IsJustPressed(input.ActionLoopEnd):
+		s.loopEnd = s.cursor()
+	case s.Input().IsJustPressed(input.ActionLoopClear):
+		s.loopStart, s.loopEnd = 0, 0
+	case s.Input().IsJustPressed(input.ActionStepBack):
+		s.step(-1)
+	case s.Input().IsJustPressed(input.ActionStepForward):
+		s.step(1)
+	}
+
+	s.play.handleKeyPress()
+
+	if player.IsPlaying() {
+		if s.hasLoop() && song.GetPositionInBPM() >= s.loopEnd {
+			song.SetPositionInBPM(s.loopStart)
+		}
+
+		if s.recording {
+			for _, direction := range gameplay.LaneIDs(song.Lanes) {
+				if s.play.keyControl.IsPressed(direction) {
+					s.placeNote(direction, s.cursor())
+				}
+			}
+		}
+	} else {
+		for _, direction := range gameplay.LaneIDs(song.Lanes) {
+			if s.play.keyControl.IsPressed(direction) {
+				s.toggleNote(direction, s.cursor())
+			}
+		}
+	}
+
+	song.Update()
+
+	return nil
+}
+
+func (s *EditorScene) Draw(screen *ebiten.Image) {
+	s.play.Draw(screen)
+	s.drawGrid(screen)
+	s.drawStatus(screen)
+}
+
+func (s *EditorScene) OnFinish() {
+	s.play.OnFinish()
+}
+
+// cursor is the song position snapped to the grid.
+func (s *EditorScene) cursor() float64 {
+	return s.snap(s.play.song.GetPositionInBPM())
+}
+
+func (s *EditorScene) snap(beat float64) float64 {
+	sub := float64(editorSubdivisions[s.subdivision])
+	return math.Round(beat*sub) / sub
+}
+
+func (s *EditorScene) step(steps int) {
+	if s.play.songPlayer.IsPlaying() {
+		return
+	}
+
+	sub := float64(editorSubdivisions[s.subdivision])
+	beat := math.Max(0, s.cursor()+float64(steps)/sub)
+	s.play.song.SetPositionInBPM(beat)
+}
+
+func (s *EditorScene) hasLoop() bool {
+	return s.loopEnd > s.loopStart
+}
+
+func (s *EditorScene) noteIndex(direction string, beat float64) int {
+	for i, n := range s.play.song.Notes {
+		if n.Direction == direction && math.Abs(n.Onset-beat) < noteEpsilon {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *EditorScene) toggleNote(direction string, beat float64) {
+	if i := s.noteIndex(direction, beat); i >= 0 {
+		song := s.play.song
+		song.Notes = append(song.Notes[:i], song.Notes[i+1:]...)
+		s.notesChanged()
+		return
+	}
+	s.placeNote(direction, beat)
+}
+
+func (s *EditorScene) placeNote(direction string, beat float64) {
+	if s.noteIndex(direction, beat) >= 0 {
+		return
+	}
+
+	song := s.play.song
+	song.Notes = append(song.Notes, &gameplay.Note{Direction: direction, Onset: beat, Judged: true})
+	sort.SliceStable(song.Notes, func(i, j int) bool {
+		return song.Notes[i].Onset < song.Notes[j].Onset
+	})
+	s.notesChanged()
+}
+
+func (s *EditorScene) notesChanged() {
+	song := s.play.song
+	song.Chart.Notes = song.Notes
+	// Keep the notes of the last beat, so a note placed on the cursor shows up.
+	song.ResetWindow(song.GetPositionInBPM() - 1)
+	s.dirty = true
+}
+
+// save writes the song with the edited chart to the save storage and makes the
+// registry use it.
+func (s *EditorScene) save() {
+	data, err := s.encodeSong()
+	if err != nil {
+		log.Printf("failed to encode chart: %v", err)
+		s.showMessage("ERROR")
+		return
+	}
+
+	if err := s.AppContext.SaveManager.Save(gamesongs.EditedChartName(s.entry.ID), data); err != nil {
+		log.Printf("failed to save chart: %v", err)
+		s.showMessage("ERROR")
+		return
+	}
+	if err := s.songs.Update(s.entry.ID, data); err != nil {
+		log.Printf("failed to update song registry: %v", err)
+	}
+
+	s.dirty = false
+	s.showMessage("SAVED")
+}
+
+// encodeSong replaces the notes of the edited chart in the original song JSON.
+// Other fields are kept as they are.
+func (s *EditorScene) encodeSong() ([]byte, error) {
+	song := s.play.song
+
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(s.entry.Data, &raw); err != nil {
+		return nil, err
+	}
+
+	notes, err := json.Marshal(song.Notes)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := raw["charts"]; !ok {
+		raw["notes"] = notes
+		return json.MarshalIndent(raw, "", "  ")
+	}
+
+	var charts []map[string]json.RawMessage
+	if err := json.Unmarshal(raw["charts"], &charts); err != nil {
+		return nil, err
+	}
+	for _, c := range charts {
+		var difficulty string
+		if err := json.Unmarshal(c["difficulty"], &difficulty); err != nil {
+			return nil, err
+		}
+		if difficulty == song.Chart.Difficulty {
+			c["notes"] = notes
+		}
+	}
+
+	if raw["charts"], err = json.Marshal(charts); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(raw, "", "  ")
+}
+
+func (s *EditorScene) showMessage(msg string) {
+	s.message = msg
+	s.messageTimer = editorMessageFrames
+}
+
+// drawGrid draws a line on the track for every grid step and loop marker.
+func (s *EditorScene) drawGrid(screen *ebiten.Image) {
+	cfg := config.Get()
+	song := s.play.song
+
+	sub := float64(editorSubdivisions[s.subdivision])
+	for beat := s.snap(song.RenderPositionInBPM()); song.ScrollProgress(beat) >= 0; beat += 1 / sub {
+		c := cfg.Colors.Medium
+		if math.Abs(beat-math.Round(beat)) < noteEpsilon {
+			c = cfg.Colors.Dark
+		}
+		s.play.drawBeatLine(screen, beat, 1, c)
+	}
+
+	if s.hasLoop() {
+		s.play.drawBeatLine(screen, s.loopStart, 2, cfg.Colors.Dark)
+		s.play.drawBeatLine(screen, s.loopEnd, 2, cfg.Colors.Dark)
+	}
+}
+
+// drawStatus covers the status column with the editor state.
+func (s *EditorScene) drawStatus(screen *ebiten.Image) {
+	cfg := config.Get()
+	ui := s.play.ui
+
+	status := s.status
+	fillStatusRectangle(status)
+
+	mode := "EDIT"
+	if s.play.songPlayer != nil && s.play.songPlayer.IsPlaying() {
+		mode = "PLAY"
+	}
+	if s.recording {
+		mode += "*"
+	}
+
+	lines := []string{
+		mode,
+		fmt.Sprintf("%.2f", s.cursor()),
+		fmt.Sprintf("1/%d", editorSubdivisions[s.subdivision]),
+	}
+	if s.hasLoop() {
+		lines = append(lines, fmt.Sprintf("%g", s.loopStart), fmt.Sprintf("%g", s.loopEnd))
+	}
+	if s.dirty {
+		lines = append(lines, "EDITED")
+	}
+	if s.messageTimer > 0 {
+		lines = append(lines, s.message)
+	}
+
+	for i, line := range lines {
+		DrawText(status, FitText(line, leftColumnWidth-statusBoxPadding), statusBoxPadding, float64(statusBoxPadding+i*uiLineHeight), cfg.Colors.Dark)
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(ui.margin+ui.trackWidth+paddingX+paddingY), float64(ui.margin+topRowHeight+paddingY*2))
+	screen.DrawImage(status, op)
+}
@@ -0,0 +1,101 @@
+package gamescene
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/core"
+	"github.com/leandroatallah/drummer/internal/engine/core/scene"
+	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gamestate "github.com/leandroatallah/drummer/internal/game/state"
+)
+
+// GameOverScene is shown when the life gauge runs out in fail mode.
+//
+// Controls:
+//   - Confirm: back to track selection
+//   - Retry: try the song again
+type GameOverScene struct {
+	scene.BaseScene
+
+	count     int
+	selection *Selection
+}
+
+func NewGameOverScene(context *core.AppContext, selection *Selection) *GameOverScene {
+	scene := GameOverScene{selection: selection}
+	scene.SetAppContext(context)
+	return &scene
+}
+
+func (s *GameOverScene) OnStart() {
+	s.AudioManager().PauseAll()
+	s.AudioManager().SetBusFade(audiomanager.BusMusic, 1)
+	s.AudioManager().PlaySound(bgSound)
+	tapAnywhere(s.AppContext, input.ActionConfirm)
+
+	s.EnableKeys()
+}
+
+func (s *GameOverScene) Update() error {
+	s.count++
+
+	if s.IsKeysDisabled {
+		return nil
+	}
+
+	switch {
+	case s.Input().IsJustPressed(input.ActionConfirm):
+		s.DisableKeys()
+		s.Manager.NavigateTo(SceneTrackSelection, transition.NewFader(), true)
+	case s.Input().IsJustPressed(input.ActionRetry):
+		s.DisableKeys()
+		if result := s.selection.Result; result != nil && result.Watched {
+			s.selection.Replay = result.Replay
+		}
+		s.Manager.NavigateTo(ScenePlay, transition.NewFader(), true)
+	}
+
+	return nil
+}
+
+func (s *GameOverScene) Draw(screen *ebiten.Image) {
+	cfg := config.Get()
+	screen.Fill(cfg.Colors.Dark)
+
+	title := "GAME OVER"
+	titleY := cfg.ScreenHeight/2 - uiLineHeight*3
+	if (s.count/20)%2 == 0 {
+		DrawText(screen, title, float64(cfg.ScreenWidth-len(title)*uiCharWidth)/2, float64(titleY), cfg.Colors.Light)
+	}
+
+	result := s.selection.Result
+	if result == nil {
+		return
+	}
+
+	width := cfg.ScreenWidth - resultsMargin*2
+	lines := []string{
+		FitText(result.Title, width),
+		fmt.Sprintf("%s  %06d", result.Difficulty, result.Score),
+		fmt.Sprintf("REACHED %d%%", int(result.Progress*100)),
+	}
+	for i, line := range lines {
+		c := cfg.Colors.Medium
+		if i == len(lines)-1 {
+			c = cfg.Colors.Light
+		}
+		x := float64(cfg.ScreenWidth-len(line)*uiCharWidth) / 2
+		DrawText(screen, line, x, float64(titleY+(i+2)*uiLineHeight), c)
+	}
+
+	hint := "R RETRY"
+	DrawText(screen, hint, float64(cfg.ScreenWidth-len(hint)*uiCharWidth)/2, float64(cfg.ScreenHeight-resultsMargin-uiLineHeight), cfg.Colors.Medium)
+}
+
+func (s *GameOverScene) OnFinish() {
+	setGameState(s.AppContext, gamestate.MainMenu)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/leandroatallah/drummer/internal/engine/core/scene"
 	"github.com/leandroatallah/drummer/internal/engine/core/screenutil"
 	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
 )
 
 const (
@@ -67,14 +68,14 @@ func (s *IntroScene) Draw(screen *ebiten.Image) {
 func (s *IntroScene) Update() error {
 	// TODO: REMOVE THIS
 	// FORCE SKIP
-	if ebiten.IsKeyPressed(ebiten.KeyEnter) {
+	if s.Input().IsPressed(input.ActionConfirm) {
 		s.NextScene()
 	}
 
 	s.count++
 
 	// Allow user to skip
-	if s.introAnimation == duration && ebiten.IsKeyPressed(ebiten.KeyEnter) {
+	if s.introAnimation == duration && s.Input().IsPressed(input.ActionConfirm) {
 		s.duration = 0
 		s.introAnimation = fadeOut
 	}
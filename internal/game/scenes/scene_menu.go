@@ -4,25 +4,48 @@ import (
 	"image"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/leandroatallah/drummer/internal/config"
 	"github.com/leandroatallah/drummer/internal/engine/assets"
 	"github.com/leandroatallah/drummer/internal/engine/assets/font"
 	"github.com/leandroatallah/drummer/internal/engine/core"
 	"github.com/leandroatallah/drummer/internal/engine/core/scene"
 	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
 )
 
 const (
 	bgSound = "assets/audio/black-sabbath-paranoid.ogg"
+
+	// menuBarMargin is the space below the option bar.
+	menuBarMargin = 2
+)
+
+type menuOption int
+
+const (
+	menuPlay menuOption = iota
+	menuSettings
+	menuCalibrate
 )
 
+var menuOptions = []string{"PLAY", "SETTINGS", "CALIBRATE"}
+
 var pressStartImg *ebiten.Image
 
+// MenuScene is the title screen. A bar at the bottom shows the chosen option.
+//
+// Controls:
+//   - Left and Right: choose an option
+//   - Confirm: select the option
+//   - Tap: the left or right half of the bar chooses, above it selects
 type MenuScene struct {
 	scene.BaseScene
 
 	count          int
 	fontText       *font.FontText
 	showPressStart bool
+	cursor         int
 }
 
 func NewMenuScene(context *core.AppContext) *MenuScene {
@@ -38,6 +61,14 @@ func (s *MenuScene) OnStart() {
 	if !s.AudioManager().IsPlayingSomething() {
 		s.AudioManager().PlayMusic(bgSound)
 	}
+
+	bar := menuBarRect()
+	middle := (bar.Min.X + bar.Max.X) / 2
+	s.AppContext.InputManager.SetZones([]input.Zone{
+		{Rect: image.Rect(0, 0, config.Get().ScreenWidth, bar.Min.Y), Action: input.ActionConfirm},
+		{Rect: image.Rect(bar.Min.X, bar.Min.Y, middle, bar.Max.Y), Action: input.ActionLeft},
+		{Rect: image.Rect(middle, bar.Min.Y, bar.Max.X, bar.Max.Y), Action: input.ActionRight},
+	})
 }
 
 func (s *MenuScene) Update() error {
@@ -47,14 +78,51 @@ func (s *MenuScene) Update() error {
 		s.showPressStart = !s.showPressStart
 	}
 
-	if !s.IsKeysDisabled && ebiten.IsKeyPressed(ebiten.KeyEnter) {
-		s.DisableKeys()
-		s.Manager.NavigateTo(SceneTrackSelection, transition.NewFader(), false)
+	if s.IsKeysDisabled {
+		return nil
+	}
+
+	switch {
+	case s.Input().IsJustPressed(input.ActionLeft):
+		s.cursor = (s.cursor + len(menuOptions) - 1) % len(menuOptions)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionRight):
+		s.cursor = (s.cursor + 1) % len(menuOptions)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionConfirm):
+		playUISound(s.AppContext)
+		s.chooseOption()
 	}
 
 	return nil
 }
 
+func (s *MenuScene) chooseOption() {
+	s.DisableKeys()
+	switch menuOption(s.cursor) {
+	case menuPlay:
+		s.Manager.NavigateTo(SceneTrackSelection, transition.NewFader(), false)
+	case menuSettings:
+		s.Manager.NavigateTo(SceneSettings, transition.NewFader(), true)
+	case menuCalibrate:
+		s.Manager.NavigateTo(SceneCalibration, transition.NewFader(), true)
+	}
+}
+
+// menuBarRect is where the option bar is drawn: wide enough for the longest
+// option between arrows, centered at the bottom of the screen.
+func menuBarRect() image.Rectangle {
+	cfg := config.Get()
+	longest := 0
+	for _, option := range menuOptions {
+		longest = max(longest, len(option))
+	}
+	width := (longest + 4) * uiCharWidth
+	x := (cfg.ScreenWidth - width) / 2
+	y := cfg.ScreenHeight - uiLineHeight - menuBarMargin
+	return image.Rect(x, y, x+width, y+uiLineHeight)
+}
+
 func (s *MenuScene) Draw(screen *ebiten.Image) {
 	frameOX, frameOY := 0, 0
 	frameSprites := 2
@@ -72,6 +140,14 @@ func (s *MenuScene) Draw(screen *ebiten.Image) {
 	).(*ebiten.Image)
 
 	DrawCenteredImage(screen, res)
+
+	cfg := config.Get()
+	bar := menuBarRect()
+	vector.DrawFilledRect(screen, float32(bar.Min.X), float32(bar.Min.Y), float32(bar.Dx()), float32(bar.Dy()), cfg.Colors.Dark, false)
+	label := menuOptions[s.cursor]
+	DrawText(screen, "<", float64(bar.Min.X), float64(bar.Min.Y), cfg.Colors.Medium)
+	DrawText(screen, label, float64(bar.Min.X+(bar.Dx()-len(label)*uiCharWidth)/2), float64(bar.Min.Y), cfg.Colors.Light)
+	DrawText(screen, ">", float64(bar.Max.X-uiCharWidth), float64(bar.Min.Y), cfg.Colors.Medium)
 }
 
 func (s *MenuScene) OnFinish() {}
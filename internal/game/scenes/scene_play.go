@@ -1,25 +1,34 @@
 package gamescene
 
 import (
-	"math"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2/audio"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/leandroatallah/drummer/internal/config"
 	"github.com/leandroatallah/drummer/internal/engine/actors"
 	"github.com/leandroatallah/drummer/internal/engine/assets"
 	"github.com/leandroatallah/drummer/internal/engine/assets/font"
+	"github.com/leandroatallah/drummer/internal/engine/contracts/navigation"
 	"github.com/leandroatallah/drummer/internal/engine/core"
 	"github.com/leandroatallah/drummer/internal/engine/core/scene"
 	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
 	gameplayer "github.com/leandroatallah/drummer/internal/game/actors/player"
+	gameplay "github.com/leandroatallah/drummer/internal/game/play"
+	gamereplay "github.com/leandroatallah/drummer/internal/game/replay"
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+	gamesave "github.com/leandroatallah/drummer/internal/game/save"
+	gamestate "github.com/leandroatallah/drummer/internal/game/state"
 )
 
 const (
-	thermometerLimit = 25
-
 	// UI
 	screenMargin      = 4
 	paddingX          = 4
@@ -29,6 +38,9 @@ const (
 	statusBoxPadding  = 2
 	scoreHeight       = 22
 	thermometerHeight = 22
+
+	// judgementData is the data file of the timing windows and their effects.
+	judgementData = "judgement.json"
 )
 
 var (
@@ -36,6 +48,7 @@ var (
 	illustrationLight *ebiten.Image
 	drummerIdleImg    *ebiten.Image
 	drummerRockImg    *ebiten.Image
+	drummerErrorImg   *ebiten.Image
 	arrowsLightImg    *ebiten.Image
 	arrowsDarkImg     *ebiten.Image
 	textsImg          *ebiten.Image
@@ -76,70 +89,180 @@ type PlayScene struct {
 	count          int
 	mainText       *font.FontText
 	levelCompleted bool
-	score          int
-	streak         int
-	thermometer    int // thermometer starts on 0 and can range from -10 to 10
 	ui             *ScreenUI
 	keyControl     *KeyControl
 	mainTrack      *MainTrack
-	song           *Song
+	song           *gameplay.Song
+	session        *gameplay.Session
 	speed          float64
 	songPlayer     *audio.Player
 	isOver         bool
-
-	// Caching layers for draw optimization
+	selection      *Selection
+	records        *Records
+	timeline       []int
+	// replay is the session being recorded, or the one being watched when
+	// replayer is set.
+	replay   *gamereplay.Replay
+	replayer *gamereplay.Player
+	// practice is set in practice mode.
+	practice *practice
+	// failFrames counts the frames of the failure transition.
+	failFrames int
+	// pause is set while the song is paused. Live input is ignored before
+	// resumeAt, the song time where the song was paused last.
+	pause    *pauseMenu
+	resumeAt float64
+	// bindings are the player controls, put back when the scene ends. The
+	// lanes of the chart may play with their own bindings meanwhile.
+	bindings input.Bindings
+	// events follows the visual effects of the chart. The poses and the
+	// illustrations they swap in are loaded on start.
+	events       *gameplay.Timeline
+	drummerPoses map[string]*ebiten.Image
+	eventImages  map[string]*ebiten.Image
+
+	// Caching layers for draw optimization. They are made once, in OnStart,
+	// and redrawn in place.
 	staticLayer         *ebiten.Image
 	scoreLayer          *ebiten.Image
 	thermometerLayer    *ebiten.Image
 	illustrationLayer   *ebiten.Image
+	dynamicLayer        *ebiten.Image
+	topRowLayer         *ebiten.Image
 	isScoreDirty        bool
 	isThermometerDirty  bool
 	isIllustrationDirty bool
+	// illustrationBeat and illustrationImage are the beat and the chart
+	// illustration the illustration layer was drawn for.
+	illustrationBeat  int
+	illustrationImage string
+	drawOp            ebiten.DrawImageOptions
+	// The frames of the drummer, the titles and the digits are cut from their
+	// sheets once, as each cut makes an image.
+	drummerFrames    map[*ebiten.Image][]*ebiten.Image
+	scoreTitle       *ebiten.Image
+	thermometerTitle *ebiten.Image
+	digitImages      [10]*ebiten.Image
+	// digits holds the number being drawn, topRowState what the top row layer
+	// shows, and frameEvents the input of the frame, so that a frame
+	// allocates nothing.
+	digits      []byte
+	topRowState topRowState
+	topRowReady bool
+	frameEvents []gamereplay.Event
 }
 
-func NewPlayScene(context *core.AppContext) *PlayScene {
-	// TODO: Should receive from somewhere (maybe context)
+// NewPlayScene loads the selected song. Songs that can't be played are an
+// error, and the scene map goes back to track selection.
+func NewPlayScene(context *core.AppContext, selection *Selection, records *Records, settings *gamesave.Settings) (*PlayScene, error) {
+	if selection.Song == nil {
+		return nil, errors.New("no song selected")
+	}
+
 	scene := &PlayScene{
-		BaseScene:   *scene.NewScene(),
-		ui:          NewScreenUI(context),
-		keyControl:  NewKeyControl(),
-		speed:       2.0,
-		thermometer: 0,
+		BaseScene:  *scene.NewScene(),
+		ui:         NewScreenUI(context),
+		keyControl: NewKeyControl(),
+		selection:  selection,
+		records:    records,
 	}
 
-	songData := context.DataManager.Get("smell-like-teen-spirit.json")
-	song := NewSongFromData(songData, scene)
+	difficulty := selection.Song.Chart(selection.Difficulty).Difficulty
+	cfg := config.Get()
+	audioOffset, visualOffset := cfg.AudioOffset, cfg.VisualOffset
+
+	if replay := selection.Replay; replay != nil {
+		selection.Replay = nil
+		if !replay.Matches(selection.Song.Data, replay.Difficulty) {
+			log.Printf("replay was recorded on a different version of the chart")
+		}
+		// Judge with the offsets of the recorded session.
+		difficulty = replay.Difficulty
+		audioOffset = time.Duration(replay.AudioOffsetMs) * time.Millisecond
+		visualOffset = time.Duration(replay.VisualOffsetMs) * time.Millisecond
+		scene.replay = replay
+		scene.replayer = gamereplay.NewPlayer(replay)
+	} else if selection.Practice {
+		selection.Practice = false
+		scene.practice = newPractice()
+	} else {
+		scene.replay = gamereplay.New(selection.Song.ID, difficulty, selection.Song.Data, int(audioOffset.Milliseconds()), int(visualOffset.Milliseconds()))
+	}
+
+	song, err := gameplay.NewSongFromData(selection.Song.Data, difficulty, playerClock{scene})
+	if err != nil {
+		return nil, fmt.Errorf("song %s: %w", selection.Song.ID, err)
+	}
+	song.SetOffsets(audioOffset, visualOffset)
+	song.SetScrollSpeed(cfg.ScrollSpeed)
 
 	scene.song = song
+	scene.speed = song.Chart.Speed
+	scene.session = gameplay.NewSession(song, gamerhythm.NewJudge(judgementConfig(context)))
+	scene.session.OnEffect = func(gamerhythm.Effect) {
+		scene.isScoreDirty = true
+		scene.isThermometerDirty = true
+		scene.isIllustrationDirty = true
+	}
+	scene.events = gameplay.NewTimeline(song.Events)
+	scene.mainTrack, err = NewMainTrack(scene)
+	if err != nil {
+		return nil, fmt.Errorf("song %s: %w", selection.Song.ID, err)
+	}
 
-	song.offsetBpm = 4 / scene.speed
-	scene.mainTrack = NewMainTrack(scene)
+	// Practice never fails, and a replay fails like the session it recorded.
+	failMode := false
+	switch {
+	case scene.replayer != nil:
+		failMode = scene.replay.FailMode
+	case scene.practice == nil:
+		failMode = settings.FailMode
+		scene.replay.FailMode = failMode
+	}
+	if failMode {
+		scene.session.EnableFailMode()
+	}
 
 	// scene.SetAppContext(context)
-	return scene
+	return scene, nil
 }
 
 func (s *PlayScene) OnStart() {
 	s.BaseScene.OnStart()
-	cfg := config.Get()
 
 	// Init images
 	illustrationLight = assets.LoadImageFromFs(s.AppContext, "assets/images/illustration-light.png")
 	illustrationDark = assets.LoadImageFromFs(s.AppContext, "assets/images/illustration-dark.png")
 	drummerIdleImg = assets.LoadImageFromFs(s.AppContext, "assets/images/drummer-idle.png")
 	drummerRockImg = assets.LoadImageFromFs(s.AppContext, "assets/images/drummer-rock.png")
+	drummerErrorImg = assets.LoadImageFromFs(s.AppContext, "assets/images/drummer-error.png")
 	arrowsLightImg = assets.LoadImageFromFs(s.AppContext, arrowsLightPath)
 	arrowsDarkImg = assets.LoadImageFromFs(s.AppContext, arrowsDarkPath)
+	s.loadEventImages()
+
+	s.buildLayers()
+
+	s.Input().SetZones(s.touchZones())
+	s.bindings = s.Input().Bindings()
+	s.Input().SetBindings(laneBindings(s.bindings, s.song.Lanes))
+}
+
+// buildLayers makes the cached layers and pre-renders the static background.
+func (s *PlayScene) buildLayers() {
+	cfg := config.Get()
 
 	// --- Initialize Layers ---
-	s.staticLayer = ebiten.NewImage(cfg.ScreenWidth, cfg.ScreenHeight)
-	s.scoreLayer = ebiten.NewImage(leftColumnWidth, scoreHeight)
-	s.thermometerLayer = ebiten.NewImage(leftColumnWidth, thermometerHeight)
+	s.staticLayer = newImage(cfg.ScreenWidth, cfg.ScreenHeight)
+	s.scoreLayer = newImage(leftColumnWidth, scoreHeight)
+	s.thermometerLayer = newImage(leftColumnWidth, thermometerHeight)
 	illustrationHeight := s.ui.innerHeight - scoreHeight - thermometerHeight - (paddingY * 2)
-	s.illustrationLayer = ebiten.NewImage(leftColumnWidth, illustrationHeight)
+	s.illustrationLayer = newImage(leftColumnWidth, illustrationHeight)
+	s.dynamicLayer = newImage(s.ui.containerWidth, s.ui.containerHeight)
+	s.topRowLayer = newImage(s.ui.innerWidth-statusBoxPadding*2, uiLineHeight)
+	s.topRowReady = false
 
 	// --- Pre-render Static Backgrounds ---
-	s.staticLayer.Fill(cfg.Colors.Medium) // Main screen background
+	// The main screen background is left out, as it flashes.
 
 	// The main UI container
 	container := s.DrawScreen()
@@ -147,7 +270,7 @@ func (s *PlayScene) OnStart() {
 	containerOp.GeoM.Translate(float64(s.ui.margin), float64(s.ui.margin))
 
 	// The drummer area background
-	drummerBg := ebiten.NewImage(s.ui.innerWidth, topRowHeight)
+	drummerBg := newImage(s.ui.innerWidth, topRowHeight)
 	drummerBg.Fill(cfg.Colors.Medium)
 	drummerBgOp := &ebiten.DrawImageOptions{}
 	drummerBgOp.GeoM.Translate(float64(paddingX), float64(paddingY))
@@ -159,41 +282,92 @@ func (s *PlayScene) OnStart() {
 	// Draw the fully prepared static container to the static layer
 	s.staticLayer.DrawImage(container, containerOp)
 
+	s.cutDrummerFrames()
+	s.cutTexts()
+
 	// --- Set Dirty Flags for First Render ---
 	s.isScoreDirty = true
 	s.isThermometerDirty = true
 	s.isIllustrationDirty = true
 }
 
+// touchZones are the lanes, the status column, which activates the bonus, and
+// the drummer row, which pauses.
+func (s *PlayScene) touchZones() []input.Zone {
+	columnX := s.ui.margin + paddingX + s.ui.trackWidth
+	columnY := s.ui.margin + topRowHeight + paddingY*2
+	zones := s.mainTrack.laneZones()
+	return append(zones,
+		input.Zone{
+			Rect:   image.Rect(columnX, columnY, s.ui.margin+s.ui.containerWidth, columnY+s.ui.innerHeight),
+			Action: input.ActionBonus,
+		},
+		input.Zone{
+			Rect:   image.Rect(0, 0, config.Get().ScreenWidth, columnY),
+			Action: input.ActionPause,
+		},
+	)
+}
+
 func (s *PlayScene) Update() error {
 	s.count++
 
 	// Wait menu sound end before start
 	if s.songPlayer == nil && !s.AudioManager().IsPlayingSomething() {
-		s.AudioManager().SetVolume(1)
+		s.AudioManager().SetBusFade(audiomanager.BusMusic, 1)
 		s.songPlayer = s.AudioManager().PlaySound("assets/audio/" + s.song.Filename)
+		setGameState(s.AppContext, gamestate.Playing)
+	}
+
+	// Nothing moves while paused
+	if s.pause != nil {
+		s.updatePause()
+		return nil
+	}
+
+	// The life gauge ran out
+	if s.session.Failed {
+		s.updateFailure()
+		return nil
 	}
 
 	// The soung is over
 	if !s.isOver && s.songPlayer != nil && !s.songPlayer.IsPlaying() {
-		s.isOver = true
-		s.DisableKeys()
-		s.AppContext.SceneManager.NavigateTo(SceneThanks, transition.NewFader(), true)
+		s.finish(SceneResults)
 	}
 
 	if s.songPlayer != nil && s.songPlayer.IsPlaying() {
-		s.handleKeyPress()
-		s.handleRightKeys()
+		if s.Input().IsJustPressed(input.ActionPause) {
+			s.openPause()
+			return nil
+		}
+		if s.practice != nil {
+			s.updatePractice()
+		}
+
+		events := s.handleKeyPress()
+		if s.replay != nil && s.replayer == nil {
+			for _, e := range events {
+				s.replay.Record(e)
+			}
+		}
+		s.session.Step(events)
+		s.events.Update(s.song.RenderPositionInBPM())
 		s.mainTrack.Update()
-		s.song.Update()
+		s.sampleThermometer()
 	}
 
 	return nil
 }
 
 func (s *PlayScene) Draw(screen *ebiten.Image) {
-	// 1. Draw the static background, which is already composed.
-	screen.DrawImage(s.staticLayer, nil)
+	// 1. Draw the static background, which is already composed, over the
+	// main screen background. Everything in the container shakes.
+	fill(screen, s.backgroundColor())
+	shakeX, shakeY := s.shakeOffset()
+	s.drawOp.GeoM.Reset()
+	s.drawOp.GeoM.Translate(shakeX, shakeY)
+	screen.DrawImage(s.staticLayer, &s.drawOp)
 
 	// --- Handle semi-static layers that need updating ---
 
@@ -209,41 +383,132 @@ func (s *PlayScene) Draw(screen *ebiten.Image) {
 		s.isThermometerDirty = false
 	}
 
-	// Redraw illustration only if it has changed.
-	s.redrawIllustrationLayer()
+	// Redraw illustration only if it has changed, or on a new beat.
+	beat, illustration := int(s.song.RenderPositionInBPM()), s.events.Image()
+	if s.isIllustrationDirty || beat != s.illustrationBeat || illustration != s.illustrationImage {
+		s.illustrationBeat, s.illustrationImage = beat, illustration
+		s.redrawIllustrationLayer()
+		s.isIllustrationDirty = false
+	}
 
 	// --- Draw the cached layers to the screen at their correct positions ---
-	containerOriginX := float64(s.ui.margin)
-	containerOriginY := float64(s.ui.margin)
+	containerOriginX := float64(s.ui.margin) + shakeX
+	containerOriginY := float64(s.ui.margin) + shakeY
+
+	columnX := containerOriginX + float64(s.ui.trackWidth+paddingX+paddingY)
 
-	scoreOp := &ebiten.DrawImageOptions{}
-	scoreOp.GeoM.Translate(containerOriginX+float64(s.ui.trackWidth+paddingX+paddingY), containerOriginY+float64(topRowHeight+(paddingY*2)))
-	screen.DrawImage(s.scoreLayer, scoreOp)
+	s.drawOp.GeoM.Reset()
+	s.drawOp.GeoM.Translate(columnX, containerOriginY+float64(topRowHeight+(paddingY*2)))
+	screen.DrawImage(s.scoreLayer, &s.drawOp)
 
-	thermometerOp := &ebiten.DrawImageOptions{}
-	thermometerOp.GeoM.Translate(containerOriginX+float64(s.ui.trackWidth+paddingX+paddingY), containerOriginY+float64(topRowHeight+scoreHeight+(paddingY*3)))
-	screen.DrawImage(s.thermometerLayer, thermometerOp)
+	s.drawOp.GeoM.Reset()
+	s.drawOp.GeoM.Translate(columnX, containerOriginY+float64(topRowHeight+scoreHeight+(paddingY*3)))
+	screen.DrawImage(s.thermometerLayer, &s.drawOp)
 
-	illustrationOp := &ebiten.DrawImageOptions{}
-	illustrationOp.GeoM.Translate(containerOriginX+float64(s.ui.trackWidth+paddingX+paddingY), containerOriginY+float64(topRowHeight+scoreHeight+thermometerHeight+(paddingY*4)))
-	screen.DrawImage(s.illustrationLayer, illustrationOp)
+	s.drawOp.GeoM.Reset()
+	s.drawOp.GeoM.Translate(columnX, containerOriginY+float64(topRowHeight+scoreHeight+thermometerHeight+(paddingY*4)))
+	screen.DrawImage(s.illustrationLayer, &s.drawOp)
 
 	// --- Draw fully dynamic elements directly on top ---
-	// We draw them into a temporary transparent image so their local coordinates match the container.
-	dynamicContainer := ebiten.NewImage(s.ui.containerWidth, s.ui.containerHeight)
-	s.drawDrummer(dynamicContainer)
-	s.mainTrack.Draw(dynamicContainer)
-
-	// Draw the dynamic container onto the screen.
-	dynamicContainerOp := &ebiten.DrawImageOptions{}
-	dynamicContainerOp.GeoM.Translate(float64(s.ui.margin), float64(s.ui.margin))
-	screen.DrawImage(dynamicContainer, dynamicContainerOp)
+	// We draw them into a transparent layer so their local coordinates match the container.
+	s.dynamicLayer.Clear()
+	s.drawDrummer(s.dynamicLayer)
+	s.mainTrack.Draw(s.dynamicLayer)
+
+	// Draw the dynamic layer onto the screen.
+	s.drawOp.GeoM.Reset()
+	s.drawOp.GeoM.Translate(containerOriginX, containerOriginY)
+	screen.DrawImage(s.dynamicLayer, &s.drawOp)
+
+	s.drawTextPopups(screen)
+	s.drawTopRowStatus(screen)
+	if s.practice != nil {
+		s.drawPractice(screen)
+	}
+	if s.pause != nil {
+		s.drawPause(screen)
+	}
+	if s.session.Failed {
+		s.drawFailure(screen)
+	}
 }
 
 func (s *PlayScene) OnFinish() {
 	if s.songPlayer != nil {
 		s.songPlayer.Pause()
 	}
+	s.closePractice()
+	if s.bindings != nil {
+		s.Input().SetBindings(s.bindings)
+	}
+	// The game over scene leaves the game over state itself.
+	if !s.session.Failed {
+		setGameState(s.AppContext, gamestate.MainMenu)
+	}
+}
+
+// finish records the play session and shows its outcome in the next scene.
+func (s *PlayScene) finish(next navigation.SceneType) {
+	s.isOver = true
+	s.DisableKeys()
+	result := s.result()
+	// Watching a replay or practicing doesn't count as a play.
+	if s.replay != nil && s.replayer == nil {
+		result.NewBest = s.records.Submit(result)
+		if err := s.replay.Save(s.AppContext.SaveManager); err != nil {
+			log.Printf("failed to save replay: %v", err)
+		}
+	}
+	s.selection.Result = &result
+	s.AppContext.SceneManager.NavigateTo(next, transition.NewFader(), true)
+}
+
+// result summarizes the play session.
+func (s *PlayScene) result() Result {
+	return Result{
+		SongID:      s.selection.Song.ID,
+		Title:       s.song.Title,
+		Difficulty:  s.song.Chart.Difficulty,
+		Score:       s.session.Score,
+		MaxStreak:   s.session.MaxStreak,
+		Tally:       s.session.Tally,
+		Cleared:     s.isOver && !s.session.Failed && s.session.Tally.Passed(),
+		Failed:      s.session.Failed,
+		Progress:    s.progress(),
+		Thermometer: s.timeline,
+		Replay:      s.replay,
+		Watched:     s.replayer != nil,
+		Practice:    s.practice != nil,
+	}
+}
+
+// progress tells how far into the song the session got, from 0 to 1.
+func (s *PlayScene) progress() float64 {
+	if s.session.Failed && s.song.Duration > 0 {
+		return min(s.session.FailedAt/s.song.Duration, 1)
+	}
+	return 1
+}
+
+// sampleThermometer keeps the thermometer value of each beat for the results graph.
+func (s *PlayScene) sampleThermometer() {
+	for beat := int(s.song.GetPositionInBPM()); len(s.timeline) <= beat; {
+		s.timeline = append(s.timeline, s.session.Thermometer)
+	}
+}
+
+// judgementConfig reads the timing windows, effects and combo tiers from the
+// game data. The defaults are used when the data is missing or invalid.
+func judgementConfig(context *core.AppContext) gamerhythm.JudgementConfig {
+	data := context.DataManager.Get(judgementData)
+	if data == nil {
+		return gamerhythm.DefaultJudgementConfig()
+	}
+	cfg, err := gamerhythm.ParseJudgementConfig(data)
+	if err != nil {
+		log.Printf("invalid judgement data: %v", err)
+	}
+	return cfg
 }
 
 func createPlayer(appContext *core.AppContext) (actors.PlayerEntity, error) {
@@ -257,86 +522,68 @@ func createPlayer(appContext *core.AppContext) (actors.PlayerEntity, error) {
 
 // DrawContainer was removed as it's no longer used by the optimized Draw method.
 
-func (s *PlayScene) handleKeyPress() {
+// handleKeyPress updates the key state with the lane events of this frame and
+// returns them. Events come from the keyboard, or from the replay being watched.
+func (s *PlayScene) handleKeyPress() []gamereplay.Event {
 	s.keyControl.Reset()
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
-		s.keyControl.PressLeft()
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
-		s.keyControl.PressDown()
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
-		s.keyControl.PressUp()
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
-		s.keyControl.PressRight()
+	events := s.laneEvents()
+	for _, e := range events {
+		if e.Lane == gameplay.BonusLane {
+			continue
+		}
+		if e.Pressed {
+			s.keyControl.Press(e.Lane)
+		} else {
+			s.keyControl.Release(e.Lane)
+		}
 	}
+	return events
 }
 
-func (s *PlayScene) handleRightKeys() {
-	tolerance := 0.5
-
-	if !s.keyControl.IsSomeKeyPressed() {
-		return
+func (s *PlayScene) laneEvents() []gamereplay.Event {
+	now := s.song.Seconds()
+	if s.replayer != nil {
+		return s.replayer.Poll(now)
 	}
 
-	hasAnyCorrect := false
-	for _, n := range s.song.PlayingNotes {
-		if n.skip {
-			continue
-		}
-
-		position := float64(n.Onset) - s.song.GetPositionInBPM()
-		if math.Abs(position) > tolerance {
-			continue
-		}
+	if now < s.resumeAt {
+		return nil
+	}
 
+	events := s.frameEvents[:0]
+	for i, lane := range s.song.Lanes {
+		action := input.LaneActions[i]
 		switch {
-		case s.keyControl.isLeftPressed && n.Direction == "left":
-			s.IncreaseScore()
-			hasAnyCorrect = true
-			n.skip = true
-		case s.keyControl.isDownPressed && n.Direction == "down":
-			s.IncreaseScore()
-			hasAnyCorrect = true
-			n.skip = true
-		case s.keyControl.isUpPressed && n.Direction == "up":
-			s.IncreaseScore()
-			hasAnyCorrect = true
-			n.skip = true
-		case s.keyControl.isRightPressed && n.Direction == "right":
-			s.IncreaseScore()
-			hasAnyCorrect = true
-			n.skip = true
+		case s.Input().IsJustPressed(action):
+			events = append(events, gamereplay.Event{Time: now, Lane: lane.ID, Pressed: true})
+		case s.Input().IsJustReleased(action):
+			events = append(events, gamereplay.Event{Time: now, Lane: lane.ID})
 		}
 	}
-
-	if !hasAnyCorrect {
-		s.handleMistake()
+	if s.Input().IsJustPressed(input.ActionBonus) {
+		events = append(events, gamereplay.Event{Time: now, Lane: gameplay.BonusLane, Pressed: true})
 	}
+	s.frameEvents = events
+	return events
 }
 
-func (s *PlayScene) IncreaseScore() {
-	s.score += 5
-	s.streak++
-	s.thermometer++
-	if s.thermometer > thermometerLimit {
-		s.thermometer = thermometerLimit
-	}
-
-	s.isScoreDirty = true
-	s.isThermometerDirty = true
-	s.isIllustrationDirty = true
+// playerClock reads the song position from the audio player of the scene,
+// which only exists once the song started.
+type playerClock struct {
+	scene *PlayScene
 }
 
-func (s *PlayScene) handleMistake() {
-	s.streak = 0
-	s.thermometer -= 2
-	if s.thermometer < 0 {
-		s.thermometer = 0
+func (c playerClock) Current() time.Duration {
+	if c.scene.songPlayer == nil {
+		return 0
 	}
+	return c.scene.songPlayer.Current()
+}
 
-	s.isThermometerDirty = true
-	s.isIllustrationDirty = true
+func (c playerClock) SetPosition(offset time.Duration) error {
+	if c.scene.songPlayer == nil {
+		return nil
+	}
+	return c.scene.songPlayer.SetPosition(offset)
 }
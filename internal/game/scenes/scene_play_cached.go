@@ -1,6 +1,10 @@
 package gamescene
 
-import "github.com/hajimehoshi/ebiten/v2"
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
 
 type CachedImage struct {
 	image   *ebiten.Image
@@ -14,3 +18,36 @@ func (i *CachedImage) DrawTo(screen *ebiten.Image) {
 func (i *CachedImage) DrawOver(img *ebiten.Image) {
 	i.image.DrawImage(img, nil)
 }
+
+// newImage makes a render target of the play scene. The benchmark replaces it
+// to count the images made while a song plays.
+var newImage = ebiten.NewImage
+
+// pixel is a white pixel, stretched and tinted by fillRect.
+var (
+	pixel   *ebiten.Image
+	pixelOp ebiten.DrawImageOptions
+)
+
+// fillRect fills a rectangle of dst with c. vector.DrawFilledRect builds its
+// path on the heap on every call, and the play scene fills rectangles every
+// frame.
+func fillRect(dst *ebiten.Image, x, y, width, height float32, c color.RGBA) {
+	if pixel == nil {
+		pixel = newImage(1, 1)
+		pixel.Fill(color.White)
+	}
+	pixelOp.GeoM.Reset()
+	pixelOp.GeoM.Scale(float64(width), float64(height))
+	pixelOp.GeoM.Translate(float64(x), float64(y))
+	pixelOp.ColorScale.Reset()
+	pixelOp.ColorScale.Scale(float32(c.R)/0xff, float32(c.G)/0xff, float32(c.B)/0xff, float32(c.A)/0xff)
+	dst.DrawImage(pixel, &pixelOp)
+}
+
+// fill paints the whole of dst with c, which must be opaque. Unlike
+// dst.Fill, it doesn't put c in a color.Color on the heap.
+func fill(dst *ebiten.Image, c color.RGBA) {
+	bounds := dst.Bounds()
+	fillRect(dst, float32(bounds.Min.X), float32(bounds.Min.Y), float32(bounds.Dx()), float32(bounds.Dy()), c)
+}
@@ -3,23 +3,33 @@ package gamescene
 import (
 	"fmt"
 	"image"
+	"strconv"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/systems/timestretch"
+	gameplay "github.com/leandroatallah/drummer/internal/game/play"
 )
 
 const (
 	arrowsLightPath = "assets/images/light-arrows.png"
 	arrowsDarkPath  = "assets/images/dark-arrows.png"
+
+	bonusMeterWidth  = 40
+	bonusMeterHeight = 3
+
+	// drummerFrameWidth is the width of a frame of the drummer sprite sheets.
+	drummerFrameWidth = 147
 )
 
 func (s *PlayScene) DrawScreen() *ebiten.Image {
 	cfg := config.Get()
 
-	container := ebiten.NewImage(s.ui.containerWidth, s.ui.containerHeight)
+	container := newImage(s.ui.containerWidth, s.ui.containerHeight)
 	container.Fill(cfg.Colors.Dark)
 
-	inner := ebiten.NewImage(s.ui.innerWidth, s.ui.innerHeight)
+	inner := newImage(s.ui.innerWidth, s.ui.innerHeight)
 	innerOp := &ebiten.DrawImageOptions{}
 	innerOp.GeoM.Translate(
 		float64(paddingX), float64(topRowHeight+(paddingY*2)),
@@ -30,43 +40,106 @@ func (s *PlayScene) DrawScreen() *ebiten.Image {
 	return container
 }
 
+// drawDrummer draws the frame of the drummer for the current beat over the
+// drummer row of the static layer.
 func (s *PlayScene) drawDrummer(screen *ebiten.Image) {
-	cfg := config.Get()
-
-	drummer := ebiten.NewImage(s.ui.innerWidth, topRowHeight)
-	drummer.Fill(cfg.Colors.Medium)
-	drummerOp := &ebiten.DrawImageOptions{}
-	drummerOp.GeoM.Translate(float64(paddingX), float64(paddingY))
-
 	var drummerImg *ebiten.Image
 	switch {
-	case s.thermometer == thermometerLimit:
+	case s.drummerPoses[s.events.Pose()] != nil:
+		drummerImg = s.drummerPoses[s.events.Pose()]
+	case s.session.Thermometer == gameplay.ThermometerLimit:
 		drummerImg = drummerRockImg
 	default:
 		drummerImg = drummerIdleImg
 	}
 
-	frameOX, frameOY := 0, 0
-	frameSprites := 2
-	width := drummerImg.Bounds().Dx() / frameSprites
-	height := drummerImg.Bounds().Dy()
+	frames := s.drummerFrames[drummerImg]
+	res := frames[int(s.song.BeatInMeasure())%len(frames)]
+
+	s.drawOp.GeoM.Reset()
+	s.drawOp.GeoM.Translate(float64(paddingX), float64(paddingY))
+	screen.DrawImage(res, &s.drawOp)
+}
+
+// cutDrummerFrames cuts the frames of the drummer sprite sheets, each to the
+// size of the row.
+func (s *PlayScene) cutDrummerFrames() {
+	s.drummerFrames = make(map[*ebiten.Image][]*ebiten.Image)
+	sheets := []*ebiten.Image{drummerIdleImg, drummerRockImg}
+	for _, img := range s.drummerPoses {
+		sheets = append(sheets, img)
+	}
+	for _, img := range sheets {
+		if img == nil || s.drummerFrames[img] != nil {
+			continue
+		}
+		width, height := drummerFrameWidth, img.Bounds().Dy()
+		frames := make([]*ebiten.Image, max(img.Bounds().Dx()/width, 1))
+		for i := range frames {
+			sx := i * width
+			frames[i] = img.SubImage(image.Rect(sx, 0, sx+min(width, s.ui.innerWidth), min(height, topRowHeight))).(*ebiten.Image)
+		}
+		s.drummerFrames[img] = frames
+	}
+}
 
-	elementWidth := drummerImg.Bounds().Dx()
-	frameCount := elementWidth / width
-	i := int(s.song.GetPositionInBPM()) % frameCount
-	sx, sy := frameOX+i*width, frameOY
+// topRowState is what the label of the top row shows.
+type topRowState struct {
+	multiplier int
+	rateIndex  int
+	mode       timestretch.Mode
+}
 
-	res := drummerImg.SubImage(
-		image.Rect(sx, sy, sx+width, sy+height),
-	).(*ebiten.Image)
+// drawTopRowStatus shows the score multiplier, and the playback speed in
+// practice mode, at the right of the drummer row. The bonus meter is below
+// them, and blinks while it drains.
+func (s *PlayScene) drawTopRowStatus(screen *ebiten.Image) {
+	cfg := config.Get()
+	now := s.song.Seconds()
+	right := s.ui.margin + paddingX + s.ui.innerWidth - statusBoxPadding
+	top := s.ui.margin + paddingY
+
+	// The label is drawn on its layer again only when what it shows changes,
+	// as drawing text allocates.
+	state := topRowState{multiplier: s.session.Multiplier(now), rateIndex: -1}
+	if s.practice != nil {
+		state.rateIndex, state.mode = s.practice.rateIndex, s.practice.mode
+	}
+	width := s.topRowLayer.Bounds().Dx()
+	if state != s.topRowState || !s.topRowReady {
+		var labels []string
+		if s.practice != nil {
+			labels = append(labels, s.practice.label())
+		}
+		if state.multiplier > 1 {
+			labels = append(labels, fmt.Sprintf("x%d", state.multiplier))
+		}
+		label := strings.Join(labels, " ")
+		s.topRowLayer.Clear()
+		DrawText(s.topRowLayer, label, float64(width-len(label)*uiCharWidth), 0, cfg.Colors.Dark)
+		s.topRowState, s.topRowReady = state, true
+	}
+	s.drawLayerImage(screen, s.topRowLayer, right-width, top+(topRowHeight-uiLineHeight)/2)
 
-	drummer.DrawImage(res, nil)
+	if len(s.song.Phrases) == 0 {
+		return
+	}
 
-	screen.DrawImage(drummer, drummerOp)
+	charge := s.session.BonusMeter
+	if s.session.BonusActive(now) {
+		if (s.count/8)%2 == 1 {
+			return
+		}
+		charge = s.session.BonusEnd - s.song.GetPositionInBPM()
+	}
+	x := float32(right - bonusMeterWidth)
+	y := float32(top + topRowHeight - bonusMeterHeight)
+	fillRect(screen, x, y, bonusMeterWidth, bonusMeterHeight, cfg.Colors.Light)
+	fillRect(screen, x, y, float32(bonusMeterWidth*charge/gameplay.BonusLimit), bonusMeterHeight, cfg.Colors.Dark)
 }
 
 func (s *PlayScene) drawStatusColumn(screen *ebiten.Image) {
-	status := ebiten.NewImage(leftColumnWidth, s.ui.innerHeight)
+	status := newImage(leftColumnWidth, s.ui.innerHeight)
 	statusOp := &ebiten.DrawImageOptions{}
 	statusOp.GeoM.Translate(float64(s.ui.trackWidth+paddingY+paddingX), topRowHeight+(paddingY*2))
 
@@ -74,108 +147,106 @@ func (s *PlayScene) drawStatusColumn(screen *ebiten.Image) {
 }
 
 func (s *PlayScene) redrawScoreLayer() {
-	s.scoreLayer.Clear()
-	score := DrawStatusRectangle(leftColumnWidth, scoreHeight)
-	scoreTitle := s.ui.textsImg.SubImage(image.Rect(0, 0, 29, 7)).(*ebiten.Image)
-	scoreTitleOp := &ebiten.DrawImageOptions{}
-	scoreTitleOp.GeoM.Translate(float64(statusBoxPadding), float64(statusBoxPadding))
-	scoreAmount := ebiten.NewImage(35, 7)
-	scoreStr := fmt.Sprintf("%06d", s.score)
-	for i := 0; i < 6; i++ {
-		v := scoreStr[i : i+1]
-		n := GetImageNumber(s.ui.textsImg, string(v))
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(float64(i*6), 0)
-		scoreAmount.DrawImage(n, op)
-	}
-	scoreAmountOp := &ebiten.DrawImageOptions{}
-	scoreAmountOp.GeoM.Translate(float64(statusBoxPadding), 11)
-	score.DrawImage(scoreTitle, scoreTitleOp)
-	score.DrawImage(scoreAmount, scoreAmountOp)
-
-	s.scoreLayer.DrawImage(score, nil)
+	fillStatusRectangle(s.scoreLayer)
+	s.drawLayerImage(s.scoreLayer, s.scoreTitle, statusBoxPadding, statusBoxPadding)
+	s.digits = appendPadded(s.digits[:0], s.session.Score, 6)
+	s.drawNumber(s.scoreLayer, s.digits, statusBoxPadding, 11)
 }
 
 func (s *PlayScene) redrawThermometerLayer() {
-	s.thermometerLayer.Clear()
 	cfg := config.Get()
-	thermometer := DrawStatusRectangle(leftColumnWidth, thermometerHeight)
+	fillStatusRectangle(s.thermometerLayer)
 
-	thermTitle := s.ui.textsImg.SubImage(image.Rect(0, 8, 29, 15)).(*ebiten.Image)
-	thermOp := &ebiten.DrawImageOptions{}
-	thermOp.GeoM.Translate(float64(statusBoxPadding), float64(statusBoxPadding))
-	thermometer.DrawImage(thermTitle, thermOp)
+	s.drawLayerImage(s.thermometerLayer, s.thermometerTitle, statusBoxPadding, statusBoxPadding)
 
-	therm := s.thermometer / 5
+	therm := s.session.Thermometer / 5
 	blockWidth := 8
 	blockHeight := 5
-	block := ebiten.NewImage(blockWidth, blockHeight)
-	blockHollow := ebiten.NewImage(blockWidth-2, blockHeight-2)
-	blockHollow.Fill(cfg.Colors.Light)
 	for i := 0; i < 5; i++ {
+		x := float32(statusBoxPadding + blockWidth*i + i*1)
+		y := float32(statusBoxPadding + 9)
 		if i < therm {
-			block.Fill(cfg.Colors.Dark)
+			fillRect(s.thermometerLayer, x, y, float32(blockWidth), float32(blockHeight), cfg.Colors.Dark)
 		} else {
-			block.Fill(cfg.Colors.Medium)
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(1, 1)
-			block.DrawImage(blockHollow, op)
+			fillRect(s.thermometerLayer, x, y, float32(blockWidth), float32(blockHeight), cfg.Colors.Medium)
+			fillRect(s.thermometerLayer, x+1, y+1, float32(blockWidth-2), float32(blockHeight-2), cfg.Colors.Light)
 		}
-		blockOp := &ebiten.DrawImageOptions{}
-		blockOp.GeoM.Translate(float64(statusBoxPadding+blockWidth*i+i*1), float64(statusBoxPadding+9))
-		thermometer.DrawImage(block, blockOp)
 	}
-
-	s.thermometerLayer.DrawImage(thermometer, nil)
 }
 
+// redrawIllustrationLayer draws the illustration, which swaps colors every
+// beat, and the streak over it.
 func (s *PlayScene) redrawIllustrationLayer() {
-	s.illustrationLayer.Clear()
-	illustrationHeight := s.ui.innerHeight - scoreHeight - thermometerHeight - (paddingY * 2)
-
 	cfg := config.Get()
-	illustration := DrawStatusRectangle(leftColumnWidth, illustrationHeight)
-
-	img := illustrationLight
-	if int(s.song.GetPositionInBPM())%2 == 0 {
-		illustration.Fill(cfg.Colors.Medium)
-		img = illustrationDark
+	fillStatusRectangle(s.illustrationLayer)
+
+	// The chart may swap the illustration for one of its own.
+	if img := s.eventImages[s.illustrationImage]; img != nil {
+		DrawCenteredImage(s.illustrationLayer, img)
+	} else {
+		img := illustrationLight
+		if s.illustrationBeat%2 == 0 {
+			fill(s.illustrationLayer, cfg.Colors.Medium)
+			img = illustrationDark
+		}
+		DrawCenteredImage(s.illustrationLayer, img)
 	}
-	DrawCenteredImage(illustration, img)
 
 	// streak
-	streakStr := fmt.Sprintf("%d", s.streak)
-	streakTxt := ebiten.NewImage(6*len(streakStr), 8)
-	for i, c := range fmt.Sprintf("%d", s.streak) {
-		txt := GetImageNumber(s.ui.textsImg, string(c))
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(float64(1+6*i), 0)
-		streakTxt.DrawImage(txt, op)
-	}
+	s.digits = strconv.AppendInt(s.digits[:0], int64(s.session.Streak), 10)
+	fillRect(s.illustrationLayer, 1, 1, float32(3+len(s.digits)*6), 12, cfg.Colors.Light)
+	s.drawNumber(s.illustrationLayer, s.digits, 3, 3)
+}
 
-	streakRect := ebiten.NewImage(3+len(streakStr)*6, 12)
-	streakRect.Fill(cfg.Colors.Light)
-	streakRectOp := &ebiten.DrawImageOptions{}
-	streakRectOp.GeoM.Translate(1, 1)
-	DrawCenteredImage(streakRect, streakTxt)
+// drawLayerImage draws img on a layer with its top-left corner at x, y.
+func (s *PlayScene) drawLayerImage(layer, img *ebiten.Image, x, y int) {
+	s.drawOp.GeoM.Reset()
+	s.drawOp.GeoM.Translate(float64(x), float64(y))
+	layer.DrawImage(img, &s.drawOp)
+}
 
-	illustration.DrawImage(streakRect, streakRectOp)
+// drawNumber draws digits with the number font of the texts image.
+func (s *PlayScene) drawNumber(layer *ebiten.Image, digits []byte, x, y int) {
+	for i, c := range digits {
+		if c >= '0' && c <= '9' {
+			s.drawLayerImage(layer, s.digitImages[c-'0'], x+i*6, y)
+		}
+	}
+}
 
-	s.illustrationLayer.DrawImage(illustration, nil)
+// appendPadded appends n in decimal, with zeros in front up to width digits.
+func appendPadded(dst []byte, n, width int) []byte {
+	digits := 1
+	for m := n; m >= 10; m /= 10 {
+		digits++
+	}
+	for ; digits < width; digits++ {
+		dst = append(dst, '0')
+	}
+	return strconv.AppendInt(dst, int64(n), 10)
+}
+
+// cutTexts cuts the titles and the digits of the texts image.
+func (s *PlayScene) cutTexts() {
+	s.scoreTitle = s.ui.textsImg.SubImage(image.Rect(0, 0, 29, 7)).(*ebiten.Image)
+	s.thermometerTitle = s.ui.textsImg.SubImage(image.Rect(0, 8, 29, 15)).(*ebiten.Image)
+	for i := range s.digitImages {
+		s.digitImages[i] = GetImageNumber(s.ui.textsImg, strconv.Itoa(i))
+	}
 }
 
 func DrawStatusRectangle(width, height int) *ebiten.Image {
+	container := newImage(width, height)
+	fillStatusRectangle(container)
+	return container
+}
+
+// fillStatusRectangle paints a status box over the whole of img.
+func fillStatusRectangle(img *ebiten.Image) {
 	cfg := config.Get()
-	container := ebiten.NewImage(width, height)
-	container.Fill(cfg.Colors.Light)
+	fill(img, cfg.Colors.Light)
 	squareSize := 2
-	square := ebiten.NewImage(squareSize, squareSize)
-	square.Fill(cfg.Colors.Dark)
-	squareOp := &ebiten.DrawImageOptions{}
-	squareOp.GeoM.Translate(float64(width-squareSize), 0)
-	container.DrawImage(square, squareOp)
-
-	return container
+	fillRect(img, float32(img.Bounds().Dx()-squareSize), 0, float32(squareSize), float32(squareSize), cfg.Colors.Dark)
 }
 
 func DrawCenteredImage(screen *ebiten.Image, image *ebiten.Image) {
@@ -251,18 +322,3 @@ func GetArrows(img *ebiten.Image) (*ebiten.Image, *ebiten.Image, *ebiten.Image,
 
 	return left, down, up, right
 }
-
-func newMovingKey(size int, img *ebiten.Image) (*ebiten.Image, *ebiten.DrawImageOptions) {
-	cfg := config.Get()
-	key := ebiten.NewImage(size, size)
-	key.Fill(cfg.Colors.Medium)
-	keyInner := ebiten.NewImage(size-2, size-2)
-	keyInner.Fill(cfg.Colors.Dark)
-	keyInnerOp := &ebiten.DrawImageOptions{}
-	keyInnerOp.GeoM.Translate(1, 1)
-	key.DrawImage(keyInner, keyInnerOp)
-	DrawCenteredImage(key, img)
-	op := &ebiten.DrawImageOptions{}
-
-	return key, op
-}
@@ -0,0 +1,191 @@
+package gamescene
+
+import (
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/leandroatallah/drummer/internal/config"
+	gameplay "github.com/leandroatallah/drummer/internal/game/play"
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+)
+
+type benchClock struct {
+	now time.Duration
+}
+
+func (c *benchClock) Current() time.Duration {
+	return c.now
+}
+
+// newBenchScene is a play scene on a chart of 2,000 notes, four per beat over
+// the four lanes with a hold every eighth note, without audio or assets.
+func newBenchScene(b *testing.B, clock *benchClock) *PlayScene {
+	lanes := []string{"left", "down", "up", "right"}
+	notes := make([]gameplay.Note, 2000)
+	for i := range notes {
+		notes[i] = gameplay.Note{Direction: lanes[i%len(lanes)], Onset: 2 + float64(i)/4}
+		if i%8 == 0 {
+			notes[i].Length = 1
+		}
+	}
+	data, err := json.Marshal(map[string]any{
+		"title":    "Benchmark",
+		"filename": "benchmark.ogg",
+		"bpm":      120,
+		"duration": 300,
+		"notes":    notes,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	song, err := gameplay.NewSongFromData(data, "", clock)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	cfg := config.Get()
+	illustrationLight = ebiten.NewImage(51, 24)
+	illustrationDark = ebiten.NewImage(51, 24)
+	drummerIdleImg = ebiten.NewImage(294, 21)
+	drummerRockImg = ebiten.NewImage(294, 21)
+	drummerErrorImg = ebiten.NewImage(588, 21)
+	arrowsLightImg = ebiten.NewImage(44, 15)
+	arrowsDarkImg = ebiten.NewImage(44, 15)
+
+	margin := 4
+	width := cfg.ScreenWidth - (margin * 2)
+	height := cfg.ScreenHeight - (margin * 2)
+	innerWidth := width - (paddingX * 2)
+	s := &PlayScene{
+		ui: &ScreenUI{
+			margin:          margin,
+			containerWidth:  width,
+			containerHeight: height,
+			innerWidth:      innerWidth,
+			innerHeight:     height - (paddingY * 2) - topRowHeight - paddingY,
+			trackWidth:      innerWidth - paddingY - leftColumnWidth,
+			textsImg:        ebiten.NewImage(29, 31),
+		},
+		keyControl: NewKeyControl(),
+		song:       song,
+		session:    gameplay.NewSession(song, gamerhythm.NewJudge(gamerhythm.DefaultJudgementConfig())),
+		events:     gameplay.NewTimeline(song.Events),
+	}
+	s.session.OnEffect = func(gamerhythm.Effect) {
+		s.isScoreDirty = true
+		s.isThermometerDirty = true
+		s.isIllustrationDirty = true
+	}
+	mainTrack, err := NewMainTrack(s)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s.mainTrack = mainTrack
+	s.buildLayers()
+	return s
+}
+
+// counted reports whether an allocation counts against the frames. Outside of
+// the game loop, as in the benchmark, ebiten copies every draw to run it once
+// the first frame starts, where the game draws at once: those copies are left
+// out, with the allocations of heapAllocs itself.
+func counted(stack []uintptr) bool {
+	caller := ""
+	for _, pc := range stack {
+		name := runtime.FuncForPC(pc - 1).Name()
+		if strings.HasSuffix(name, "/scenes.heapAllocs") {
+			return false
+		}
+		if caller == "" && !strings.HasPrefix(name, "runtime.") {
+			caller = name
+		}
+	}
+	return caller != "github.com/hajimehoshi/ebiten/v2/internal/atlas.(*Image).DrawTriangles" &&
+		caller != "github.com/hajimehoshi/ebiten/v2/internal/atlas.appendDeferred"
+}
+
+// memProfile holds the records read by heapAllocs. It is made with room to
+// spare, so reading the profile doesn't allocate between two reads.
+var memProfile []runtime.MemProfileRecord
+
+// heapAllocs returns the objects and bytes allocated so far that count
+// against the frames. Allocations are only all recorded with a memory
+// profile rate of 1.
+func heapAllocs() (objects, bytes int64) {
+	var records []runtime.MemProfileRecord
+	for {
+		runtime.GC()
+		n, ok := runtime.MemProfile(memProfile, true)
+		if ok {
+			records = memProfile[:n]
+			break
+		}
+		memProfile = make([]runtime.MemProfileRecord, n+1024)
+	}
+	for _, r := range records {
+		if counted(r.Stack()) {
+			objects += r.AllocObjects
+			bytes += r.AllocBytes
+		}
+	}
+	return objects, bytes
+}
+
+// BenchmarkPlayFrame draws the play scene frame by frame, and reports the
+// images made and the heap allocations per frame once the layers and sprites
+// are built.
+func BenchmarkPlayFrame(b *testing.B) {
+	clock := &benchClock{}
+	s := newBenchScene(b, clock)
+	screen := ebiten.NewImage(config.Get().ScreenWidth, config.Get().ScreenHeight)
+
+	// The chart is over after 252 seconds, and starts over.
+	frame := func() {
+		clock.now += time.Second / 60
+		if clock.now > 252*time.Second {
+			clock.now = 0
+			s.song.ResetWindow(0)
+			s.song.ClearJudgements(0)
+		}
+		s.session.Step(nil)
+		s.Draw(screen)
+	}
+	// The first frame builds the track sprites, and the first seconds fill the
+	// track, with the buffers that follow it.
+	for range 600 {
+		frame()
+	}
+
+	images := 0
+	defer func(create func(int, int) *ebiten.Image) { newImage = create }(newImage)
+	newImage = func(width, height int) *ebiten.Image {
+		images++
+		return ebiten.NewImage(width, height)
+	}
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+	objects, bytes := heapAllocs()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		frame()
+	}
+	b.StopTimer()
+	allObjects, allBytes := heapAllocs()
+	objects, bytes = allObjects-objects, allBytes-bytes
+
+	b.ReportMetric(float64(images)/float64(b.N), "images/op")
+	b.ReportMetric(float64(objects)/float64(b.N), "allocs/op")
+	b.ReportMetric(float64(bytes)/float64(b.N), "B/op")
+	if images != 0 {
+		b.Errorf("%d images made in %d frames, want none", images, b.N)
+	}
+	if objects != 0 {
+		b.Errorf("%d heap allocations in %d frames, want none", objects, b.N)
+	}
+}
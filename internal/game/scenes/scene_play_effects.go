@@ -0,0 +1,119 @@
+package gamescene
+
+import (
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/leandroatallah/drummer/internal/config"
+	gameplay "github.com/leandroatallah/drummer/internal/game/play"
+)
+
+// textPopupRise is how many pixels a text pop-up moves up while it is on.
+const textPopupRise = 6
+
+// loadEventImages loads the illustrations and poses the chart events swap in,
+// so nothing is loaded while the song plays.
+func (s *PlayScene) loadEventImages() {
+	s.drummerPoses = map[string]*ebiten.Image{
+		"idle":  drummerIdleImg,
+		"rock":  drummerRockImg,
+		"error": drummerErrorImg,
+	}
+
+	s.eventImages = make(map[string]*ebiten.Image)
+	for _, e := range s.song.Events {
+		if e.Type != gameplay.EventIllustration || e.Image == "" || s.eventImages[e.Image] != nil {
+			continue
+		}
+		img, _, err := ebitenutil.NewImageFromFileSystem(s.AppContext.Assets, "assets/images/"+e.Image)
+		if err != nil {
+			log.Printf("failed to load illustration of the chart: %v", err)
+			continue
+		}
+		s.eventImages[e.Image] = img
+	}
+}
+
+// eventColor returns the palette color an event names.
+func eventColor(name string, fallback color.RGBA) color.RGBA {
+	cfg := config.Get()
+	switch name {
+	case "light":
+		return cfg.Colors.Light
+	case "medium":
+		return cfg.Colors.Medium
+	case "dark":
+		return cfg.Colors.Dark
+	}
+	return fallback
+}
+
+// backgroundColor is the color around the container, which flashes.
+func (s *PlayScene) backgroundColor() color.RGBA {
+	c := config.Get().Colors.Medium
+	for _, e := range s.events.Active() {
+		if e.Type == gameplay.EventFlash {
+			c = eventColor(e.Color, c)
+		}
+	}
+	return c
+}
+
+// shakeOffset is how far the screen is moved by the shakes on. A shake fades
+// out over its length.
+func (s *PlayScene) shakeOffset() (float64, float64) {
+	beat := s.song.RenderPositionInBPM()
+	var x, y float64
+	for _, e := range s.events.Active() {
+		if e.Type != gameplay.EventShake {
+			continue
+		}
+		strength := e.Strength
+		if strength <= 0 {
+			strength = 2
+		}
+		amount := strength * (1 - e.Progress(beat))
+		x += math.Round(amount * math.Sin(float64(s.count)*2.1))
+		y += math.Round(amount * math.Cos(float64(s.count)*1.7))
+	}
+	return x, y
+}
+
+// drawHighlights paints the highlighted lanes on the canvas.
+func (t *MainTrack) drawHighlights() {
+	s := t.scene
+	for _, e := range s.events.Active() {
+		if e.Type != gameplay.EventHighlight {
+			continue
+		}
+		c := eventColor(e.Color, config.Get().Colors.Medium)
+		for _, tr := range t.tracks {
+			if tr.lane.ID == e.Lane {
+				fillRect(t.canvas, float32(tr.x), 0, float32(tr.width), float32(s.ui.innerHeight), c)
+			}
+		}
+	}
+}
+
+// drawTextPopups draws the texts on over the track, rising as they go.
+func (s *PlayScene) drawTextPopups(screen *ebiten.Image) {
+	cfg := config.Get()
+	beat := s.song.RenderPositionInBPM()
+	trackX := s.ui.margin + paddingX
+	trackY := s.ui.margin + topRowHeight + paddingY*2
+
+	for _, e := range s.events.Active() {
+		if e.Type != gameplay.EventText {
+			continue
+		}
+		label := FitText(e.Text, s.ui.trackWidth-2)
+		width := len(label)*uiCharWidth + 2
+		x := trackX + (s.ui.trackWidth-width)/2
+		y := trackY + s.ui.innerHeight/3 - int(e.Progress(beat)*textPopupRise)
+		fillRect(screen, float32(x), float32(y), float32(width), uiLineHeight, cfg.Colors.Dark)
+		DrawText(screen, label, float64(x+1), float64(y), cfg.Colors.Light)
+	}
+}
@@ -0,0 +1,48 @@
+package gamescene
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/leandroatallah/drummer/internal/config"
+	gamestate "github.com/leandroatallah/drummer/internal/game/state"
+)
+
+// failTransitionFrames is how long the track takes to close once the life
+// gauge runs out in fail mode.
+const failTransitionFrames = 90
+
+// updateFailure plays the failure transition, then opens the game over scene.
+func (s *PlayScene) updateFailure() {
+	if s.failFrames == 0 {
+		s.DisableKeys()
+		if s.practice != nil && s.practice.player != nil {
+			s.practice.player.Pause()
+		}
+		setGameState(s.AppContext, gamestate.GameOver)
+	}
+
+	s.failFrames++
+	if s.failFrames == failTransitionFrames {
+		s.finish(SceneGameOver)
+	}
+}
+
+// drawFailure closes the track like a curtain and shows that the song failed.
+func (s *PlayScene) drawFailure(screen *ebiten.Image) {
+	cfg := config.Get()
+
+	x := float32(s.ui.margin + paddingX)
+	y := float32(s.ui.margin + topRowHeight + paddingY*2)
+	progress := min(float32(s.failFrames)/(failTransitionFrames/2), 1)
+	height := float32(s.ui.innerHeight) * progress
+	fillRect(screen, x, y, float32(s.ui.trackWidth), height, cfg.Colors.Dark)
+
+	if progress == 1 {
+		label := "FAILED"
+		DrawText(screen,
+			label,
+			float64(int(x)+(s.ui.trackWidth-len(label)*uiCharWidth)/2),
+			float64(int(y)+(s.ui.innerHeight-uiLineHeight)/2),
+			cfg.Colors.Light,
+		)
+	}
+}
@@ -1,39 +1,80 @@
 package gamescene
 
+import (
+	"log"
+
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gameplay "github.com/leandroatallah/drummer/internal/game/play"
+)
+
+// KeyControl keeps which lanes were pressed this frame, and which are held.
 type KeyControl struct {
-	isLeftPressed  bool
-	isDownPressed  bool
-	isUpPressed    bool
-	isRightPressed bool
+	pressed map[string]bool
+	held    map[string]bool
 }
 
 func NewKeyControl() *KeyControl {
-	return &KeyControl{}
+	return &KeyControl{
+		pressed: make(map[string]bool),
+		held:    make(map[string]bool),
+	}
 }
 
 func (k *KeyControl) Reset() {
-	k.isLeftPressed = false
-	k.isDownPressed = false
-	k.isUpPressed = false
-	k.isRightPressed = false
+	clear(k.pressed)
 }
 
 func (k *KeyControl) IsSomeKeyPressed() bool {
-	return k.isLeftPressed ||
-		k.isDownPressed ||
-		k.isUpPressed ||
-		k.isRightPressed
+	for _, pressed := range k.pressed {
+		if pressed {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPressed reports whether the key of a lane was pressed.
+func (k *KeyControl) IsPressed(lane string) bool {
+	return k.pressed[lane]
 }
 
-func (k *KeyControl) PressLeft() {
-	k.isLeftPressed = true
+// Press marks the key of a lane as pressed this frame and held until it is
+// released.
+func (k *KeyControl) Press(lane string) {
+	k.pressed[lane] = true
+	k.held[lane] = true
 }
-func (k *KeyControl) PressDown() {
-	k.isDownPressed = true
+
+// Release marks the key of a lane as no longer held.
+func (k *KeyControl) Release(lane string) {
+	k.held[lane] = false
 }
-func (k *KeyControl) PressUp() {
-	k.isUpPressed = true
+
+func (k *KeyControl) IsHeld(lane string) bool {
+	return k.held[lane]
 }
-func (k *KeyControl) PressRight() {
-	k.isRightPressed = true
+
+// laneBindings returns the bindings to play a chart with: the lanes that
+// declare their own bindings replace the player controls of their position.
+func laneBindings(base input.Bindings, lanes []gameplay.Lane) input.Bindings {
+	bindings := base.Clone()
+	for i, lane := range lanes {
+		if len(lane.Bindings) == 0 {
+			continue
+		}
+
+		var list []input.Binding
+		for _, name := range lane.Bindings {
+			b, err := input.ParseBinding(name)
+			if err != nil {
+				log.Printf("lane %q: %v", lane.ID, err)
+				continue
+			}
+			list = append(list, b)
+		}
+		if err := bindings.Assign(input.LaneActions[i], list); err != nil {
+			log.Printf("lane %q: %v", lane.ID, err)
+		}
+	}
+	return bindings
 }
@@ -0,0 +1,234 @@
+package gamescene
+
+import (
+	"image"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gamereplay "github.com/leandroatallah/drummer/internal/game/replay"
+	gamestate "github.com/leandroatallah/drummer/internal/game/state"
+)
+
+const (
+	// pauseCountdownBeats is the length of the countdown shown before the song
+	// resumes.
+	pauseCountdownBeats = 3
+	// pauseRewindBeats is how far back the song restarts after a pause, so the
+	// next notes come down the track again before they must be hit.
+	pauseRewindBeats = 2
+
+	pauseMenuPadding = 6
+	countdownScale   = 3
+)
+
+type pauseOption int
+
+const (
+	pauseResume pauseOption = iota
+	pauseRestart
+	pauseQuit
+)
+
+var pauseOptions = []string{"RESUME", "RESTART", "QUIT"}
+
+// pauseMenu is shown while the song is paused, and counts the beats down once
+// the player chose to resume.
+//
+// Controls:
+//   - Up and Down: choose an option
+//   - Confirm: select the option
+//   - Pause or Back: resume
+//   - Tap: choose and confirm an option
+type pauseMenu struct {
+	cursor int
+	// seconds is the song time when the game was paused.
+	seconds float64
+
+	countingDown   bool
+	countdownStart time.Time
+	beatDuration   time.Duration
+
+	box   *ebiten.Image
+	digit *ebiten.Image
+}
+
+// openPause stops the song and the judgement until the player resumes.
+func (s *PlayScene) openPause() {
+	now := s.song.Seconds()
+	s.songPlayer.Pause()
+	s.releaseLanes(now)
+
+	width := len(pauseOptions[pauseRestart])*uiCharWidth + uiCharWidth*2 + pauseMenuPadding*2
+	height := (len(pauseOptions)+1)*uiLineHeight + pauseMenuPadding*2
+	s.pause = &pauseMenu{
+		seconds: now,
+		box:     newImage(width, height),
+		digit:   newImage(uiCharWidth, uiLineHeight),
+	}
+	setGameState(s.AppContext, gamestate.Paused)
+}
+
+// releaseLanes releases the lanes held when the game is paused, so a hold note
+// doesn't wait for a key that may be let go during the pause. A watched replay
+// has its own releases.
+func (s *PlayScene) releaseLanes(now float64) {
+	if s.replayer != nil {
+		return
+	}
+
+	for _, lane := range s.song.Lanes {
+		if !s.keyControl.IsHeld(lane.ID) {
+			continue
+		}
+
+		e := gamereplay.Event{Time: now, Lane: lane.ID}
+		if s.replay != nil {
+			s.replay.Record(e)
+		}
+		s.keyControl.Release(lane.ID)
+		s.session.HandleEvent(e)
+	}
+}
+
+func (s *PlayScene) updatePause() {
+	p := s.pause
+	if p.countingDown {
+		if time.Since(p.countdownStart) >= p.beatDuration*pauseCountdownBeats {
+			s.resume()
+		}
+		return
+	}
+
+	switch {
+	case s.Input().IsJustPressed(input.ActionPause), s.Input().IsJustPressed(input.ActionBack):
+		s.startCountdown()
+	case s.Input().IsJustPressed(input.ActionUp):
+		p.cursor = (p.cursor + len(pauseOptions) - 1) % len(pauseOptions)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionDown):
+		p.cursor = (p.cursor + 1) % len(pauseOptions)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionConfirm):
+		playUISound(s.AppContext)
+		s.choosePauseOption()
+	}
+
+	if point, ok := s.Input().JustTapped(); ok {
+		s.tapPause(point)
+	}
+}
+
+func (s *PlayScene) choosePauseOption() {
+	switch pauseOption(s.pause.cursor) {
+	case pauseResume:
+		s.startCountdown()
+	case pauseRestart:
+		s.restart()
+	case pauseQuit:
+		s.DisableKeys()
+		s.Manager.NavigateTo(SceneTrackSelection, transition.NewFader(), true)
+	}
+}
+
+// tapPause chooses the tapped option of the pause menu.
+func (s *PlayScene) tapPause(point image.Point) {
+	box := s.pauseBoxRect()
+	row := (point.Y-box.Min.Y-pauseMenuPadding)/uiLineHeight - 1
+	if !point.In(box) || point.Y < box.Min.Y+pauseMenuPadding || row < 0 || row >= len(pauseOptions) {
+		return
+	}
+	s.pause.cursor = row
+	s.choosePauseOption()
+}
+
+// pauseBoxRect is where the pause menu is drawn, centered on the track.
+func (s *PlayScene) pauseBoxRect() image.Rectangle {
+	size := s.pause.box.Bounds().Size()
+	x := s.ui.margin + paddingX + (s.ui.trackWidth-size.X)/2
+	y := s.ui.margin + topRowHeight + paddingY*2 + (s.ui.innerHeight-size.Y)/2
+	return image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x+size.X, y+size.Y)}
+}
+
+// startCountdown moves the song back a little and counts the beats down
+// before playing again. The track shows the rewound position meanwhile.
+func (s *PlayScene) startCountdown() {
+	p := s.pause
+	beat := s.song.BeatAt(p.seconds)
+	rewind := max(beat-pauseRewindBeats, 0)
+	if err := s.songPlayer.SetPosition(s.song.ClockPosition(rewind)); err != nil {
+		log.Printf("failed to rewind song: %v", err)
+	}
+
+	p.countingDown = true
+	p.countdownStart = time.Now()
+	p.beatDuration = s.song.BeatDuration(beat)
+}
+
+func (s *PlayScene) resume() {
+	// Input is ignored until the song is back where it was paused, so the
+	// notes of the rewound part aren't judged twice and recorded events stay
+	// in time order.
+	s.resumeAt = s.pause.seconds
+	s.pause = nil
+	s.songPlayer.Play()
+	setGameState(s.AppContext, gamestate.Playing)
+}
+
+// restart plays the song again from the start, in the same mode.
+func (s *PlayScene) restart() {
+	s.DisableKeys()
+	s.selection.Practice = s.practice != nil
+	if s.replayer != nil {
+		s.selection.Replay = s.replay
+	}
+	s.Manager.NavigateTo(ScenePlay, transition.NewFader(), true)
+}
+
+// drawPause draws the pause menu over the track, or the countdown once the
+// player chose to resume.
+func (s *PlayScene) drawPause(screen *ebiten.Image) {
+	cfg := config.Get()
+	p := s.pause
+
+	trackX := s.ui.margin + paddingX
+	trackY := s.ui.margin + topRowHeight + paddingY*2
+
+	if p.countingDown {
+		left := pauseCountdownBeats - int(time.Since(p.countdownStart)/p.beatDuration)
+		p.digit.Clear()
+		DrawText(p.digit, strconv.Itoa(max(left, 1)), 0, 0, cfg.Colors.Dark)
+
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(countdownScale, countdownScale)
+		op.GeoM.Translate(
+			float64(trackX+(s.ui.trackWidth-uiCharWidth*countdownScale)/2),
+			float64(trackY+(s.ui.innerHeight-uiLineHeight*countdownScale)/2),
+		)
+		screen.DrawImage(p.digit, op)
+		return
+	}
+
+	box := p.box
+	box.Fill(cfg.Colors.Dark)
+	inner := box.SubImage(box.Bounds().Inset(1)).(*ebiten.Image)
+	inner.Fill(cfg.Colors.Light)
+
+	DrawText(box, "PAUSED", pauseMenuPadding, pauseMenuPadding, cfg.Colors.Medium)
+	for i, option := range pauseOptions {
+		label := "  " + option
+		if i == p.cursor {
+			label = "> " + option
+		}
+		DrawText(box, label, pauseMenuPadding, float64(pauseMenuPadding+(i+1)*uiLineHeight), cfg.Colors.Dark)
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	rect := s.pauseBoxRect()
+	op.GeoM.Translate(float64(rect.Min.X), float64(rect.Min.Y))
+	screen.DrawImage(box, op)
+}
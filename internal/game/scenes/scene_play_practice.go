@@ -0,0 +1,179 @@
+package gamescene
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	"github.com/leandroatallah/drummer/internal/engine/systems/timestretch"
+)
+
+// practiceRates are the playback speeds available in practice mode.
+var practiceRates = []float64{0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// practice is the state of practice mode: a looped section of the song and a
+// slower playback speed. Nothing is saved while practicing.
+//
+// Controls, with their default keys:
+//   - Loop in and Loop out (Z and X): set the loop start or end on the current
+//     beat
+//   - No loop (C): clear the loop
+//   - Slower and Faster (- and =): slow down or speed up
+//   - Stretch (M): switch between time stretch, which keeps the pitch, and
+//     resampling
+//   - Pause (Escape): pause, and quit from the pause menu
+type practice struct {
+	loopStart float64
+	loopEnd   float64
+	rateIndex int
+	mode      timestretch.Mode
+
+	// source is the decoded song, loaded the first time the speed changes.
+	source *timestretch.Source
+	// player plays source at the chosen speed.
+	player *audio.Player
+}
+
+func newPractice() *practice {
+	return &practice{rateIndex: len(practiceRates) - 1}
+}
+
+func (p *practice) rate() float64 {
+	return practiceRates[p.rateIndex]
+}
+
+func (p *practice) hasLoop() bool {
+	return p.loopEnd > p.loopStart
+}
+
+// updatePractice handles the practice controls and loops the section.
+func (s *PlayScene) updatePractice() {
+	p := s.practice
+	song := s.song
+
+	switch {
+	case s.Input().IsJustPressed(input.ActionLoopStart):
+		p.loopStart = math.Floor(song.GetPositionInBPM())
+	case s.Input().IsJustPressed(input.ActionLoopEnd):
+		p.loopEnd = math.Ceil(song.GetPositionInBPM())
+	case s.Input().IsJustPressed(input.ActionLoopClear):
+		p.loopStart, p.loopEnd = 0, 0
+	case s.Input().IsJustPressed(input.ActionSlower):
+		s.setPracticeSpeed(max(p.rateIndex-1, 0), p.mode)
+	case s.Input().IsJustPressed(input.ActionFaster):
+		s.setPracticeSpeed(min(p.rateIndex+1, len(practiceRates)-1), p.mode)
+	case s.Input().IsJustPressed(input.ActionStretch):
+		mode := timestretch.ModeResample
+		if p.mode == timestretch.ModeResample {
+			mode = timestretch.ModeStretch
+		}
+		s.setPracticeSpeed(p.rateIndex, mode)
+	}
+
+	if p.hasLoop() && song.GetPositionInBPM() >= p.loopEnd {
+		song.SetPositionInBPM(p.loopStart)
+		song.ClearJudgements(p.loopStart)
+		s.resumeAt = 0
+	}
+}
+
+// setPracticeSpeed swaps the song player for one of the decoded song played
+// at another speed, from the same song position.
+func (s *PlayScene) setPracticeSpeed(rateIndex int, mode timestretch.Mode) {
+	p := s.practice
+	if s.songPlayer == nil || (rateIndex == p.rateIndex && mode == p.mode) {
+		return
+	}
+
+	if p.source == nil {
+		source, err := s.decodeSong()
+		if err != nil {
+			log.Printf("failed to decode song for practice: %v", err)
+			return
+		}
+		p.source = source
+	}
+
+	rate := practiceRates[rateIndex]
+	player, err := s.AudioManager().NewPlayer(timestretch.NewStream(p.source, rate, mode))
+	if err != nil {
+		log.Printf("failed to create practice player: %v", err)
+		return
+	}
+
+	beat := s.song.GetPositionInBPM()
+	s.songPlayer.Pause()
+	if p.player != nil {
+		s.AudioManager().ClosePlayer(p.player)
+	}
+
+	p.rateIndex, p.mode, p.player = rateIndex, mode, player
+	s.songPlayer = player
+	s.song.SetRate(rate)
+	s.song.SetPositionInBPM(beat)
+	player.Play()
+}
+
+func (s *PlayScene) decodeSong() (*timestretch.Source, error) {
+	item, err := s.AudioManager().LoadFromFS(s.AppContext.Assets, s.selection.Song.AudioPath())
+	if err != nil {
+		return nil, err
+	}
+	stream, err := audiomanager.Decode(item.Name(), item.Data())
+	if err != nil {
+		return nil, err
+	}
+	pcm, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+	return timestretch.NewSource(pcm), nil
+}
+
+func (s *PlayScene) closePractice() {
+	if s.practice != nil && s.practice.player != nil {
+		s.AudioManager().ClosePlayer(s.practice.player)
+		s.practice.player = nil
+	}
+}
+
+// label tells the playback speed. A trailing * means the song is resampled
+// instead of time stretched.
+func (p *practice) label() string {
+	label := fmt.Sprintf("PRACTICE %d%%", int(math.Round(p.rate()*100)))
+	if p.mode == timestretch.ModeResample {
+		label += "*"
+	}
+	return label
+}
+
+// drawPractice shows the loop markers.
+func (s *PlayScene) drawPractice(screen *ebiten.Image) {
+	cfg := config.Get()
+	p := s.practice
+
+	if p.hasLoop() {
+		s.drawBeatLine(screen, p.loopStart, 2, cfg.Colors.Dark)
+		s.drawBeatLine(screen, p.loopEnd, 2, cfg.Colors.Dark)
+	}
+}
+
+// drawBeatLine draws a line across the track where a beat is, if it is on
+// screen.
+func (s *PlayScene) drawBeatLine(screen *ebiten.Image, beat float64, width float32, c color.RGBA) {
+	if p := s.song.ScrollProgress(beat); p < 0 || p > 1 {
+		return
+	}
+
+	originX := float32(s.ui.margin + paddingX)
+	originY := float32(s.ui.margin + topRowHeight + paddingY*2)
+	y := originY + float32(s.mainTrack.noteY(beat)) + float32(s.mainTrack.keySize()/2)
+	fillRect(screen, originX, y-width/2, float32(s.ui.trackWidth), width, c)
+}
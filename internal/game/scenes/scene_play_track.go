@@ -1,150 +1,279 @@
 package gamescene
 
 import (
-	"log"
+	"fmt"
+	"image"
+	"image/color"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gameplay "github.com/leandroatallah/drummer/internal/game/play"
 )
 
-type Track struct{}
+// Track is one lane of the main track.
+type Track struct {
+	lane gameplay.Lane
+	// x and width place the lane inside the track.
+	x     int
+	width int
+
+	// key and pressedKey are the sprites of the key at the bottom of the lane,
+	// note and bonusNote the ones of the keys moving down to it.
+	key        *ebiten.Image
+	pressedKey *ebiten.Image
+	note       *ebiten.Image
+	bonusNote  *ebiten.Image
+}
 
 type MainTrack struct {
-	left  Track
-	down  Track
-	up    Track
-	right Track
-	scene *PlayScene
+	// tracks are the lanes of the song, in the same order.
+	tracks []*Track
+	scene  *PlayScene
+
+	// The track is drawn on canvas every frame, over the lanes as they are or
+	// darkened while the bonus is active. The backgrounds and the sprites are
+	// built once, before the first frame, so playing makes no images.
+	canvas          *ebiten.Image
+	background      *ebiten.Image
+	bonusBackground *ebiten.Image
+	sprites         *ebiten.Image
+	op              ebiten.DrawImageOptions
+}
+
+// NewMainTrack lays the lanes of the song out on the track. Lanes too narrow
+// to be drawn are an error.
+func NewMainTrack(scene *PlayScene) (*MainTrack, error) {
+	lanes := scene.song.Lanes
+	gap := paddingX / 2
+	available := scene.ui.trackWidth - gap*(len(lanes)-1)
+
+	total := 0.0
+	for _, l := range lanes {
+		total += laneWeight(l)
+	}
+
+	t := &MainTrack{scene: scene}
+	x := 0
+	for _, l := range lanes {
+		track := &Track{lane: l, x: x, width: int(float64(available) * laneWeight(l) / total)}
+		if track.width < 1 {
+			return nil, fmt.Errorf("lane %q is too narrow to draw", l.ID)
+		}
+		x += track.width + gap
+		t.tracks = append(t.tracks, track)
+	}
+	return t, nil
 }
 
-func NewMainTrack(scene *PlayScene) *MainTrack {
-	return &MainTrack{scene: scene}
+func laneWeight(l gameplay.Lane) float64 {
+	if l.Width <= 0 {
+		return 1
+	}
+	return l.Width
 }
 
 func (t *MainTrack) Update() error {
 	return nil
 }
 
-// FIX: Long method.
-func (t *MainTrack) Draw(screen *ebiten.Image) {
+// keySize is the height of the keys, which are as tall as the narrowest lane
+// is wide.
+func (t *MainTrack) keySize() int {
+	size := t.scene.ui.trackWidth
+	for _, track := range t.tracks {
+		size = min(size, track.width)
+	}
+	return size
+}
+
+// laneZones are the touch areas of the lanes on the screen. Each lane spans the
+// whole track height and the gap before the next lane, so chords can be
+// tapped with several fingers.
+func (t *MainTrack) laneZones() []input.Zone {
 	s := t.scene
+	trackX := s.ui.margin + paddingX
+	trackY := s.ui.margin + topRowHeight + paddingY*2
 
+	zones := make([]input.Zone, len(t.tracks))
+	for i, track := range t.tracks {
+		x1 := trackX + s.ui.trackWidth
+		if i < len(t.tracks)-1 {
+			x1 = trackX + t.tracks[i+1].x
+		}
+		zones[i] = input.Zone{
+			Rect:   image.Rect(trackX+track.x, trackY, x1, trackY+s.ui.innerHeight),
+			Action: input.LaneActions[i],
+		}
+	}
+	return zones
+}
+
+// noteY returns the top position, inside a lane, of the key of a song beat.
+func (t *MainTrack) noteY(beat float64) float64 {
+	return t.scene.song.ScrollProgress(beat)*float64(t.scene.ui.innerHeight) - float64(t.keySize())
+}
+
+// Rows of the sprite sheet. Each lane has a column with its sprites.
+const (
+	keySprite = iota
+	pressedKeySprite
+	noteSprite
+	bonusNoteSprite
+	spriteRows
+)
+
+// build draws the lane backgrounds and the sprite sheet of the keys.
+func (t *MainTrack) build() {
+	s := t.scene
 	cfg := config.Get()
+	keySize := t.keySize()
 
-	track := ebiten.NewImage(s.ui.trackWidth, s.ui.innerHeight)
-	track.Fill(cfg.Colors.Dark)
+	t.canvas = newImage(s.ui.trackWidth, s.ui.innerHeight)
+	t.background = t.newBackground(cfg.Colors.Light)
+	t.bonusBackground = t.newBackground(cfg.Colors.Medium)
 
-	trackColWidth := (s.ui.trackWidth - (paddingX/2)*3) / 4
-	trackLeft := ebiten.NewImage(trackColWidth, s.ui.innerHeight)
-	trackDown := ebiten.NewImage(trackColWidth, s.ui.innerHeight)
-	trackUp := ebiten.NewImage(trackColWidth, s.ui.innerHeight)
-	trackRight := ebiten.NewImage(trackColWidth, s.ui.innerHeight)
+	t.sprites = newImage(s.ui.trackWidth, keySize*spriteRows)
+	lightArrows := arrowImages(arrowsLightImg)
+	darkArrows := arrowImages(arrowsDarkImg)
+	for _, tr := range t.tracks {
+		light, dark := lightArrows[tr.lane.Arrow], darkArrows[tr.lane.Arrow]
+		if light == nil {
+			light, dark = t.labelIcons(tr)
+		}
 
-	trackLeft.Fill(cfg.Colors.Light)
-	trackDown.Fill(cfg.Colors.Light)
-	trackUp.Fill(cfg.Colors.Light)
-	trackRight.Fill(cfg.Colors.Light)
+		tr.key = t.newSprite(tr, keySprite, cfg.Colors.Medium, cfg.Colors.Medium, light)
+		tr.pressedKey = t.newSprite(tr, pressedKeySprite, cfg.Colors.Dark, cfg.Colors.Dark, dark)
+		// Notes of bonus phrases have a light border.
+		tr.note = t.newSprite(tr, noteSprite, cfg.Colors.Medium, cfg.Colors.Dark, dark)
+		tr.bonusNote = t.newSprite(tr, bonusNoteSprite, cfg.Colors.Light, cfg.Colors.Dark, dark)
+	}
+}
 
-	// arrows bottom
-	bottomBorderHeight := paddingY / 2
-	bottomBorder := ebiten.NewImage(s.ui.trackWidth, bottomBorderHeight)
-	bottomBorder.Fill(cfg.Colors.Dark)
-	bottomBorderOp := &ebiten.DrawImageOptions{}
-	bottomBorderOp.GeoM.Translate(0, float64(s.ui.innerHeight-trackColWidth-bottomBorderHeight))
-
-	arrowsLightLeft, arrowsLightDown, arrowsLightUp, arrowsLightRight := GetArrows(arrowsLightImg)
-	arrowsDarkLeft, arrowsDarkDown, arrowsDarkUp, arrowsDarkRight := GetArrows(arrowsDarkImg)
-
-	arrowThemeMap := map[string][]*ebiten.Image{
-		"left":  {arrowsLightLeft, arrowsDarkLeft},
-		"down":  {arrowsLightDown, arrowsDarkDown},
-		"up":    {arrowsLightUp, arrowsDarkUp},
-		"right": {arrowsLightRight, arrowsDarkRight},
+// newBackground draws the lanes over the dark track.
+func (t *MainTrack) newBackground(laneColor color.Color) *ebiten.Image {
+	cfg := config.Get()
+	bg := newImage(t.scene.ui.trackWidth, t.scene.ui.innerHeight)
+	bg.Fill(cfg.Colors.Dark)
+	for _, tr := range t.tracks {
+		vector.DrawFilledRect(bg, float32(tr.x), 0, float32(tr.width), float32(t.scene.ui.innerHeight), laneColor, false)
 	}
+	return bg
+}
 
-	getArrowImageAndOp := func(direction string, isPressed bool) (*ebiten.Image, *ebiten.DrawImageOptions) {
-		arrow := ebiten.NewImage(trackColWidth, trackColWidth)
-		arrowsImg, found := arrowThemeMap[direction]
-		if !found {
-			log.Panic()
-		}
-		light := arrowsImg[0]
-		dark := arrowsImg[1]
-
-		if isPressed {
-			arrow.Fill(cfg.Colors.Dark)
-			DrawCenteredImage(arrow, dark)
-		} else {
-			arrow.Fill(cfg.Colors.Medium)
-			DrawCenteredImage(arrow, light)
-		}
-		arrowOp := &ebiten.DrawImageOptions{}
-		arrowOp.GeoM.Translate(0, float64(s.ui.innerHeight-trackColWidth))
-		return arrow, arrowOp
+// newSprite draws a key of a lane on the sprite sheet, with a one pixel border
+// around its fill and its icon in the middle, and returns it.
+func (t *MainTrack) newSprite(tr *Track, row int, border, fill color.Color, icon *ebiten.Image) *ebiten.Image {
+	keySize := t.keySize()
+	x, y := tr.x, row*keySize
+	sprite := t.sprites.SubImage(image.Rect(x, y, x+tr.width, y+keySize)).(*ebiten.Image)
+	sprite.Fill(border)
+	vector.DrawFilledRect(t.sprites, float32(x+1), float32(y+1), float32(tr.width-2), float32(keySize-2), fill, false)
+
+	// Sub-images keep the coordinates of the sheet.
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x+tr.width/2-icon.Bounds().Dx()/2), float64(y+keySize/2-icon.Bounds().Dy()/2))
+	t.sprites.DrawImage(icon, op)
+	return sprite
+}
+
+// labelIcons draws the label of a lane without an arrow, light and dark.
+func (t *MainTrack) labelIcons(tr *Track) (*ebiten.Image, *ebiten.Image) {
+	cfg := config.Get()
+	label := tr.lane.Label
+	if label == "" {
+		label = tr.lane.ID
+	}
+	label = FitText(label, max(tr.width, uiCharWidth))
+
+	light := newImage(max(len(label)*uiCharWidth, 1), uiLineHeight)
+	dark := newImage(max(len(label)*uiCharWidth, 1), uiLineHeight)
+	DrawText(light, label, 0, 0, cfg.Colors.Light)
+	DrawText(dark, label, 0, 0, cfg.Colors.Medium)
+	return light, dark
+}
+
+// arrowImages returns the arrows of an arrow sheet by direction.
+func arrowImages(img *ebiten.Image) map[string]*ebiten.Image {
+	left, down, up, right := GetArrows(img)
+	return map[string]*ebiten.Image{"left": left, "down": down, "up": up, "right": right}
+}
+
+func (t *MainTrack) Draw(screen *ebiten.Image) {
+	s := t.scene
+	cfg := config.Get()
+
+	if t.canvas == nil {
+		t.build()
+	}
+	keySize := t.keySize()
+
+	// The lanes darken while the bonus is active.
+	background := t.background
+	if s.session.BonusActive(s.song.Seconds()) {
+		background = t.bonusBackground
 	}
+	t.canvas.DrawImage(background, nil)
+	t.drawHighlights()
 
-	arrowLeft, arrowLeftOp := getArrowImageAndOp("left", s.keyControl.isLeftPressed)
-	arrowDown, arrowDownOp := getArrowImageAndOp("down", s.keyControl.isDownPressed)
-	arrowUp, arrowUpOp := getArrowImageAndOp("up", s.keyControl.isUpPressed)
-	arrowRight, arrowRightOp := getArrowImageAndOp("right", s.keyControl.isRightPressed)
-
-	trackLeft.DrawImage(arrowLeft, arrowLeftOp)
-	trackDown.DrawImage(arrowDown, arrowDownOp)
-	trackUp.DrawImage(arrowUp, arrowUpOp)
-	trackRight.DrawImage(arrowRight, arrowRightOp)
-
-	trackLeftOp := &ebiten.DrawImageOptions{}
-	trackDownOp := &ebiten.DrawImageOptions{}
-	trackUpOp := &ebiten.DrawImageOptions{}
-	trackRightOp := &ebiten.DrawImageOptions{}
-
-	for i, op := range []*ebiten.DrawImageOptions{trackLeftOp, trackDownOp, trackUpOp, trackRightOp} {
-		padding := 0
-		if i > 0 {
-			padding = paddingX / 2
+	// The keys at the bottom of the lanes
+	for _, tr := range t.tracks {
+		key := tr.key
+		if s.keyControl.IsPressed(tr.lane.ID) {
+			key = tr.pressedKey
 		}
-		op.GeoM.Translate(float64(trackColWidth*i+padding*i), 0)
+		t.drawSprite(key, tr.x, float64(s.ui.innerHeight-keySize))
 	}
 
 	// Draw moving arrows
-	for _, n := range s.song.PlayingNotes {
-		var lane *ebiten.Image
-		var arrow *ebiten.Image
-
-		switch n.Direction {
-		case "left":
-			arrow = arrowsDarkLeft
-			lane = trackLeft
-		case "down":
-			arrow = arrowsDarkDown
-			lane = trackDown
-		case "up":
-			arrow = arrowsDarkUp
-			lane = trackUp
-		case "right":
-			arrow = arrowsDarkRight
-			lane = trackRight
-		default:
-			continue
-		}
-		movingKey, movingKeyOp := newMovingKey(trackColWidth, arrow)
+	for _, v := range s.song.Playing() {
+		n := v.Note
+		tr := t.tracks[v.Lane]
 
-		progress := (s.song.GetPositionInBPM() - (float64(n.Onset) - s.song.offsetBpm)) / s.song.offsetBpm
-		offsetY := progress*float64(s.ui.innerHeight) - float64(trackColWidth)
+		offsetY := t.noteY(n.Onset)
+		if n.IsHold() {
+			// While the note is held, its head stays on the arrow.
+			if receptorY := float64(s.ui.innerHeight - keySize); n.Holding && offsetY > receptorY {
+				offsetY = receptorY
+			}
+			t.drawHoldTail(tr, t.noteY(n.End()), offsetY)
+		}
 
-		movingKeyOp.GeoM.Translate(0, offsetY)
-		lane.DrawImage(movingKey, movingKeyOp)
+		note := tr.note
+		if v.Bonus {
+			note = tr.bonusNote
+		}
+		t.drawSprite(note, tr.x, offsetY)
 	}
 
-	// Draw tracks to track container
-	track.DrawImage(trackLeft, trackLeftOp)
-	track.DrawImage(trackRight, trackRightOp)
-	track.DrawImage(trackUp, trackUpOp)
-	track.DrawImage(trackDown, trackDownOp)
-	track.DrawImage(bottomBorder, bottomBorderOp)
+	// arrows bottom
+	bottomBorderHeight := paddingY / 2
+	fillRect(t.canvas, 0, float32(s.ui.innerHeight-keySize-bottomBorderHeight), float32(s.ui.trackWidth), float32(bottomBorderHeight), cfg.Colors.Dark)
+
+	t.op.GeoM.Reset()
+	t.op.GeoM.Translate(paddingX, topRowHeight+paddingY*2)
+	screen.DrawImage(t.canvas, &t.op)
+}
 
-	trackOp := &ebiten.DrawImageOptions{}
-	trackOp.GeoM.Translate(paddingX, topRowHeight+paddingY*2)
+// drawSprite draws a sprite of the sheet on the canvas, at x, y of the track.
+func (t *MainTrack) drawSprite(sprite *ebiten.Image, x int, y float64) {
+	t.op.GeoM.Reset()
+	t.op.GeoM.Translate(float64(x), y)
+	t.canvas.DrawImage(sprite, &t.op)
+}
 
-	screen.DrawImage(track, trackOp)
+// drawHoldTail draws the sustain line of a hold note between the key of its end
+// and the key of its head.
+func (t *MainTrack) drawHoldTail(tr *Track, endY, headY float64) {
+	height := int(headY - endY)
+	if height <= 0 {
+		return
+	}
+
+	cfg := config.Get()
+	tailWidth := tr.width / 3
+	x := tr.x + tr.width/2 - tailWidth/2
+	y := endY + float64(t.keySize()/2)
+	fillRect(t.canvas, float32(x), float32(y), float32(tailWidth), float32(height), cfg.Colors.Dark)
 }
@@ -0,0 +1,147 @@
+package gamescene
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/core"
+	"github.com/leandroatallah/drummer/internal/engine/core/scene"
+	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gameplay "github.com/leandroatallah/drummer/internal/game/play"
+	gamerhythm "github.com/leandroatallah/drummer/internal/game/rhythm"
+)
+
+const (
+	resultsMargin      = 4
+	resultsGraphHeight = 36
+)
+
+// ResultsScene shows the performance breakdown of the last song.
+type ResultsScene struct {
+	scene.BaseScene
+
+	count     int
+	selection *Selection
+	records   *Records
+
+	// graph holds the thermometer graph of graphResult, drawn once per result.
+	graph       *ebiten.Image
+	graphResult *Result
+	graphOp     ebiten.DrawImageOptions
+}
+
+func NewResultsScene(context *core.AppContext, selection *Selection, records *Records) *ResultsScene {
+	scene := ResultsScene{selection: selection, records: records}
+	scene.SetAppContext(context)
+	return &scene
+}
+
+func (s *ResultsScene) OnStart() {
+	s.AudioManager().PauseAll()
+	s.AudioManager().PlaySound(bgSound)
+	tapAnywhere(s.AppContext, input.ActionConfirm)
+
+	s.EnableKeys()
+}
+
+func (s *ResultsScene) Update() error {
+	s.count++
+
+	if !s.IsKeysDisabled && s.Input().IsJustPressed(input.ActionConfirm) {
+		s.DisableKeys()
+		s.Manager.NavigateTo(SceneTrackSelection, transition.NewFader(), true)
+	}
+
+	// Watch the session again
+	if result := s.selection.Result; !s.IsKeysDisabled && result != nil && result.Replay != nil && s.Input().IsJustPressed(input.ActionRetry) {
+		s.DisableKeys()
+		s.selection.Replay = result.Replay
+		s.Manager.NavigateTo(ScenePlay, transition.NewFader(), true)
+	}
+
+	return nil
+}
+
+func (s *ResultsScene) Draw(screen *ebiten.Image) {
+	cfg := config.Get()
+	screen.Fill(cfg.Colors.Dark)
+
+	result := s.selection.Result
+	if result == nil {
+		return
+	}
+
+	width := cfg.ScreenWidth - resultsMargin*2
+	accuracy := result.Tally.Accuracy()
+
+	lines := []string{
+		FitText(result.Title, width),
+		fmt.Sprintf("%s  %06d", result.Difficulty, result.Score),
+		fmt.Sprintf("RANK %s  %.1f%%", gamerhythm.LetterGrade(accuracy), accuracy),
+		fmt.Sprintf("HIT %d  MISS %d", result.Tally.Hits(), result.Tally.Misses()),
+		fmt.Sprintf("MAX STREAK %d", result.MaxStreak),
+	}
+	for i, line := range lines {
+		c := cfg.Colors.Light
+		if i == 0 {
+			c = cfg.Colors.Medium
+		}
+		DrawText(screen, line, resultsMargin, float64(resultsMargin+i*uiLineHeight), c)
+	}
+
+	bestY := float64(resultsMargin + len(lines)*uiLineHeight)
+	if result.Watched {
+		DrawText(screen, "REPLAY", resultsMargin, bestY, cfg.Colors.Medium)
+	} else if result.Practice {
+		DrawText(screen, "PRACTICE", resultsMargin, bestY, cfg.Colors.Medium)
+	} else if result.NewBest {
+		if (s.count/20)%2 == 0 {
+			DrawText(screen, "NEW BEST!", resultsMargin, bestY, cfg.Colors.Light)
+		}
+	} else if best, ok := s.records.Best(result.SongID, result.Difficulty); ok {
+		DrawText(screen, fmt.Sprintf("BEST %06d", best.BestScore), resultsMargin, bestY, cfg.Colors.Medium)
+	}
+
+	if s.graph == nil {
+		s.graph = ebiten.NewImage(width, resultsGraphHeight)
+		s.graphOp.GeoM.Translate(resultsMargin, float64(cfg.ScreenHeight-resultsMargin-resultsGraphHeight))
+	}
+	if s.graphResult != result {
+		s.drawThermometerGraph(s.graph, result.Thermometer)
+		s.graphResult = result
+	}
+	screen.DrawImage(s.graph, &s.graphOp)
+}
+
+func (s *ResultsScene) OnFinish() {}
+
+// drawThermometerGraph plots the thermometer over the whole song on graph.
+func (s *ResultsScene) drawThermometerGraph(graph *ebiten.Image, timeline []int) {
+	cfg := config.Get()
+
+	fillStatusRectangle(graph)
+	if len(timeline) < 2 {
+		return
+	}
+
+	width, height := graph.Bounds().Dx(), graph.Bounds().Dy()
+
+	padding := float32(statusBoxPadding)
+	plotWidth := float32(width) - padding*2
+	plotHeight := float32(height) - padding*2
+
+	point := func(i int) (float32, float32) {
+		x := padding + plotWidth*float32(i)/float32(len(timeline)-1)
+		y := padding + plotHeight*(1-float32(timeline[i])/gameplay.ThermometerLimit)
+		return x, y
+	}
+
+	for i := 1; i < len(timeline); i++ {
+		x0, y0 := point(i - 1)
+		x1, y1 := point(i)
+		vector.StrokeLine(graph, x0, y0, x1, y1, 1, cfg.Colors.Dark, false)
+	}
+}
@@ -0,0 +1,245 @@
+package gamescene
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/leandroatallah/drummer/internal/config"
+	"github.com/leandroatallah/drummer/internal/engine/core"
+	"github.com/leandroatallah/drummer/internal/engine/core/scene"
+	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gamesave "github.com/leandroatallah/drummer/internal/game/save"
+	gametheme "github.com/leandroatallah/drummer/internal/game/theme"
+)
+
+const (
+	settingsMargin = 4
+
+	// Ranges of the options, and how much a step changes them.
+	volumeStep       = 0.1
+	scrollSpeedMin   = 0.5
+	scrollSpeedMax   = 3
+	scrollSpeedStep  = 0.25
+	noteOffsetLimit  = 500
+	noteOffsetStep   = 5
+	windowScaleLimit = 8
+)
+
+// settingsOption is a row of the settings scene. Options with change are
+// changed with Left and Right; the others do something on Confirm.
+type settingsOption struct {
+	label   string
+	value   func() string
+	change  func(step int)
+	confirm func()
+}
+
+// SettingsScene lists the player options. Changes take effect right away, and
+// are saved when leaving.
+//
+// Controls:
+//   - Up and Down: choose an option
+//   - Left and Right: change it
+//   - Confirm: open the controls
+//   - Back: save and return to the menu
+type SettingsScene struct {
+	scene.BaseScene
+
+	settings *gamesave.Settings
+	themes   *gametheme.Registry
+	options  []settingsOption
+	cursor   int
+}
+
+func NewSettingsScene(context *core.AppContext, settings *gamesave.Settings, themes *gametheme.Registry) *SettingsScene {
+	scene := SettingsScene{settings: settings, themes: themes}
+	scene.SetAppContext(context)
+	scene.options = scene.newOptions()
+	return &scene
+}
+
+func (s *SettingsScene) newOptions() []settingsOption {
+	st := s.settings
+	return []settingsOption{
+		{
+			label: "MUSIC",
+			value: func() string { return percent(config.Get().MusicVolume) },
+			change: func(step int) {
+				st.MusicVolume = ptr(stepFloat(config.Get().MusicVolume, step, volumeStep, 0, 1))
+			},
+		},
+		{
+			label: "SFX",
+			value: func() string { return percent(config.Get().SFXVolume) },
+			change: func(step int) {
+				st.SFXVolume = ptr(stepFloat(config.Get().SFXVolume, step, volumeStep, 0, 1))
+			},
+		},
+		{
+			label: "SPEED",
+			value: func() string { return fmt.Sprintf("x%.2f", config.Get().ScrollSpeed) },
+			change: func(step int) {
+				st.ScrollSpeed = ptr(stepFloat(config.Get().ScrollSpeed, step, scrollSpeedStep, scrollSpeedMin, scrollSpeedMax))
+			},
+		},
+		{
+			label: "OFFSET",
+			value: func() string { return fmt.Sprintf("%+dms", st.AudioOffsetMs) },
+			change: func(step int) {
+				st.AudioOffsetMs = min(max(st.AudioOffsetMs+step*noteOffsetStep, -noteOffsetLimit), noteOffsetLimit)
+			},
+		},
+		{
+			label: "SCALE",
+			value: func() string { return fmt.Sprintf("x%d", config.Get().WindowScale) },
+			change: func(step int) {
+				st.WindowScale = ptr(min(max(config.Get().WindowScale+step, 1), windowScaleLimit))
+			},
+		},
+		{
+			label: "FAIL",
+			value: func() string { return onOff(st.FailMode) },
+			change: func(int) {
+				st.FailMode = !st.FailMode
+			},
+		},
+		{
+			label: "FULLSCR",
+			value: func() string { return onOff(config.Get().Fullscreen) },
+			change: func(int) {
+				st.Fullscreen = ptr(!config.Get().Fullscreen)
+			},
+		},
+		{
+			label: "THEME",
+			value: func() string {
+				if current := s.themes.Current(); current != nil {
+					return current.Name
+				}
+				return "-"
+			},
+			change: s.changeTheme,
+		},
+		{
+			label: "CONTROLS",
+			value: func() string { return "" },
+			confirm: func() {
+				s.save()
+				s.DisableKeys()
+				s.Manager.NavigateTo(SceneControls, transition.NewFader(), true)
+			},
+		},
+	}
+}
+
+// changeTheme picks the theme before or after the current one.
+func (s *SettingsScene) changeTheme(step int) {
+	palettes := s.themes.Palettes()
+	if len(palettes) == 0 {
+		return
+	}
+	i := 0
+	for j, p := range palettes {
+		if p == s.themes.Current() {
+			i = j
+		}
+	}
+	i = (i + step + len(palettes)) % len(palettes)
+	s.settings.Theme = palettes[i].ID
+}
+
+func (s *SettingsScene) OnStart() {
+	s.EnableKeys()
+}
+
+func (s *SettingsScene) Update() error {
+	if s.IsKeysDisabled {
+		return nil
+	}
+
+	option := s.options[s.cursor]
+	switch {
+	case s.Input().IsJustPressed(input.ActionBack):
+		s.save()
+		s.DisableKeys()
+		s.Manager.NavigateTo(SceneMenu, transition.NewFader(), true)
+	case s.Input().IsJustPressed(input.ActionUp):
+		s.cursor = max(s.cursor-1, 0)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionDown):
+		s.cursor = min(s.cursor+1, len(s.options)-1)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionLeft) && option.change != nil:
+		option.change(-1)
+		playUISound(s.AppContext)
+		applySettings(s.AppContext, s.settings, s.themes)
+	case s.Input().IsJustPressed(input.ActionRight) && option.change != nil:
+		option.change(1)
+		playUISound(s.AppContext)
+		applySettings(s.AppContext, s.settings, s.themes)
+	case s.Input().IsJustPressed(input.ActionConfirm) && option.confirm != nil:
+		playUISound(s.AppContext)
+		option.confirm()
+	}
+	return nil
+}
+
+func (s *SettingsScene) save() {
+	if err := s.settings.Save(s.AppContext.SaveManager); err != nil {
+		log.Printf("failed to save settings: %v", err)
+	}
+}
+
+func (s *SettingsScene) Draw(screen *ebiten.Image) {
+	cfg := config.Get()
+	screen.Fill(cfg.Colors.Dark)
+
+	DrawText(screen, "SETTINGS", settingsMargin, settingsMargin, cfg.Colors.Medium)
+
+	// The rows between the title and the footer scroll with the cursor.
+	visible := (cfg.ScreenHeight - settingsMargin*2 - uiLineHeight*2) / uiLineHeight
+	first := max(s.cursor-visible+1, 0)
+	for row := range min(visible, len(s.options)-first) {
+		i := first + row
+		option := s.options[i]
+		prefix := " "
+		if i == s.cursor {
+			prefix = ">"
+		}
+		line := fmt.Sprintf("%s%-8s %s", prefix, option.label, option.value())
+		DrawText(screen, FitText(line, cfg.ScreenWidth-settingsMargin*2), settingsMargin, float64(settingsMargin+(row+1)*uiLineHeight), cfg.Colors.Light)
+	}
+
+	footer := "LEFT/RIGHT: change"
+	if s.options[s.cursor].confirm != nil {
+		footer = "CONFIRM: open"
+	}
+	DrawText(screen, footer, settingsMargin, float64(cfg.ScreenHeight-settingsMargin-uiLineHeight), cfg.Colors.Medium)
+}
+
+func (s *SettingsScene) OnFinish() {}
+
+// stepFloat moves value by step times size, within lo and hi. The result is
+// rounded to the step size, so repeated steps don't drift.
+func stepFloat(value float64, step int, size, lo, hi float64) float64 {
+	value = math.Round(value/size+float64(step)) * size
+	return min(max(value, lo), hi)
+}
+
+func percent(v float64) string {
+	return fmt.Sprintf("%d%%", int(math.Round(v*100)))
+}
+
+func onOff(b bool) string {
+	if b {
+		return "ON"
+	}
+	return "OFF"
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
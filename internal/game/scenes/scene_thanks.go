@@ -6,6 +6,7 @@ import (
 	"github.com/leandroatallah/drummer/internal/engine/core"
 	"github.com/leandroatallah/drummer/internal/engine/core/scene"
 	"github.com/leandroatallah/drummer/internal/engine/core/transition"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
 )
 
 var bgImg *ebiten.Image
@@ -25,10 +26,11 @@ func (s *ThanksScene) OnStart() {
 
 	s.AudioManager().PauseAll()
 	s.AudioManager().PlaySound(bgSound)
+	tapAnywhere(s.AppContext, input.ActionConfirm)
 }
 
 func (s *ThanksScene) Update() error {
-	if !s.IsKeysDisabled && ebiten.IsKeyPressed(ebiten.KeyEnter) {
+	if !s.IsKeysDisabled && s.Input().IsJustPressed(input.ActionConfirm) {
 		s.DisableKeys()
 		s.Manager.NavigateTo(SceneMenu, transition.NewFader(), true)
 	}
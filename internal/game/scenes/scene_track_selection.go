@@ -1,39 +1,66 @@
 package gamescene
 
 import (
+	"fmt"
 	"image"
+	"log"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/leandroatallah/drummer/internal/engine/assets"
-	"github.com/leandroatallah/drummer/internal/engine/assets/font"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/leandroatallah/drummer/internal/config"
 	"github.com/leandroatallah/drummer/internal/engine/core"
 	"github.com/leandroatallah/drummer/internal/engine/core/scene"
 	"github.com/leandroatallah/drummer/internal/engine/core/transition"
 	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
+	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	gamereplay "github.com/leandroatallah/drummer/internal/game/replay"
+	gamesave "github.com/leandroatallah/drummer/internal/game/save"
+	gamesongs "github.com/leandroatallah/drummer/internal/game/songs"
 )
 
-var selectionImg *ebiten.Image
+const (
+	trackListMargin = 4
+	trackRowHeight  = 42
+	trackRowGap     = 3
+	trackRowPadding = 3
+
+	// previewDelay is the number of frames the cursor must rest on a song
+	// before its preview starts.
+	previewDelay = 45
+)
 
 type TrackSelectionScene struct {
 	scene.BaseScene
 
-	count          int
-	audiomanager   *audiomanager.AudioManager
-	fontText       *font.FontText
-	showPressStart bool
+	count        int
+	audiomanager *audiomanager.AudioManager
+	songs        *gamesongs.Registry
+	selection    *Selection
+	records      *Records
+	settings     *gamesave.Settings
+	cursor       int
+	scroll       int
+	previewTimer int
+	preview      string
 }
 
-func NewTrackSelectionScene(context *core.AppContext) *TrackSelectionScene {
-	scene := TrackSelectionScene{}
+func NewTrackSelectionScene(context *core.AppContext, songs *gamesongs.Registry, selection *Selection, records *Records, settings *gamesave.Settings) *TrackSelectionScene {
+	scene := TrackSelectionScene{songs: songs, selection: selection, records: records, settings: settings}
 	scene.SetAppContext(context)
 	return &scene
 }
 
 func (s *TrackSelectionScene) OnStart() {
 	s.audiomanager = s.Manager.AudioManager()
+	s.audiomanager.FadeOut(bgSound, 1*time.Second)
 
-	selectionImg = assets.LoadImageFromFs(s.AppContext, "assets/images/track-selection.png")
+	// Start from the last played song
+	for i, entry := range s.songs.All() {
+		if s.selection.Song != nil && entry.ID == s.selection.Song.ID {
+			s.moveCursor(i)
+		}
+	}
 
 	s.EnableKeys()
 }
@@ -41,33 +68,260 @@ func (s *TrackSelectionScene) OnStart() {
 func (s *TrackSelectionScene) Update() error {
 	s.count++
 
-	if !s.IsKeysDisabled && ebiten.IsKeyPressed(ebiten.KeyEnter) {
+	if s.IsKeysDisabled {
+		return nil
+	}
+
+	switch {
+	case s.Input().IsJustPressed(input.ActionUp):
+		s.moveCursor(s.cursor - 1)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionDown):
+		s.moveCursor(s.cursor + 1)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionLeft):
+		s.changeDifficulty(-1)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionRight):
+		s.changeDifficulty(1)
+		playUISound(s.AppContext)
+	case s.Input().IsJustPressed(input.ActionBack):
+		s.DisableKeys()
+		s.Manager.NavigateTo(SceneMenu, transition.NewFader(), true)
+		return nil
+	}
+
+	s.previewTimer++
+	if s.previewTimer == previewDelay {
+		s.startPreview()
+	}
+
+	if s.Input().IsPressed(input.ActionConfirm) && s.songs.Len() > 0 {
+		s.play()
+		return nil
+	}
+
+	if p, ok := s.Input().JustTapped(); ok {
+		s.tap(p)
+		return nil
+	}
+
+	// Practice the highlighted song
+	if s.Input().IsJustPressed(input.ActionPractice) && s.songs.Len() > 0 {
 		s.DisableKeys()
+		s.selection.Song = s.songs.All()[s.cursor]
+		s.selection.Practice = true
 		s.Manager.NavigateTo(ScenePlay, transition.NewFader(), true)
 	}
 
+	// Watch the last replay of the highlighted chart
+	if s.Input().IsJustPressed(input.ActionReplay) && s.songs.Len() > 0 {
+		s.watchReplay()
+	}
+
+	// Toggle fail mode
+	if s.Input().IsJustPressed(input.ActionFailMode) {
+		s.settings.FailMode = !s.settings.FailMode
+		if err := s.settings.Save(s.AppContext.SaveManager); err != nil {
+			log.Printf("failed to save settings: %v", err)
+		}
+	}
+
+	// Open the chart editor on the highlighted song
+	if s.Input().IsJustPressed(input.ActionEditor) && s.songs.Len() > 0 {
+		s.DisableKeys()
+		s.selection.Song = s.songs.All()[s.cursor]
+		s.Manager.NavigateTo(SceneEditor, transition.NewFader(), true)
+	}
+
 	return nil
 }
 
 func (s *TrackSelectionScene) Draw(screen *ebiten.Image) {
-	frameOX, frameOY := 0, 0
-	frameSprites := 3
-	frameRate := 20
-	width := selectionImg.Bounds().Dx() / frameSprites
-	height := selectionImg.Bounds().Dy()
-
-	elementWidth := selectionImg.Bounds().Dx()
-	frameCount := elementWidth / width
-	i := (s.count / frameRate) % frameCount
-	sx, sy := frameOX+i*width, frameOY
+	cfg := config.Get()
+	screen.Fill(cfg.Colors.Medium)
 
-	res := selectionImg.SubImage(
-		image.Rect(sx, sy, sx+width, sy+height),
-	).(*ebiten.Image)
+	entries := s.songs.All()
+	if len(entries) == 0 {
+		msg := "NO SONGS"
+		DrawText(screen, msg, float64(cfg.ScreenWidth/2-len(msg)*uiCharWidth/2), float64(cfg.ScreenHeight/2-uiLineHeight/2), cfg.Colors.Dark)
+		return
+	}
 
-	DrawCenteredImage(screen, res)
+	rowWidth := cfg.ScreenWidth - trackListMargin*2
+	for row := 0; row < s.visibleRows(); row++ {
+		i := s.scroll + row
+		if i >= len(entries) {
+			break
+		}
+		s.drawRow(screen, entries[i], trackListMargin, trackListMargin+row*(trackRowHeight+trackRowGap), rowWidth, i == s.cursor)
+	}
 }
 
 func (s *TrackSelectionScene) OnFinish() {
-	s.audiomanager.FadeOut(bgSound, 1*time.Second)
+	s.stopPreview()
+}
+
+// drawRow draws the row of a song straight on the screen, at x, y.
+func (s *TrackSelectionScene) drawRow(screen *ebiten.Image, entry *gamesongs.Entry, x, y, width int, selected bool) {
+	cfg := config.Get()
+
+	left, top := float32(x), float32(y)
+	if selected && (s.count/20)%2 == 0 {
+		vector.DrawFilledRect(screen, left, top, float32(width), trackRowHeight, cfg.Colors.Light, false)
+		vector.DrawFilledRect(screen, left+1, top+1, float32(width-2), trackRowHeight-2, cfg.Colors.Dark, false)
+	} else {
+		vector.DrawFilledRect(screen, left, top, float32(width), trackRowHeight, cfg.Colors.Dark, false)
+	}
+
+	titleColor := cfg.Colors.Medium
+	if selected {
+		titleColor = cfg.Colors.Light
+	}
+
+	chart := entry.Chart(s.selection.Difficulty)
+	textWidth := width - trackRowPadding*2
+	textX := float64(x + trackRowPadding)
+	DrawText(screen, FitText(entry.Title, textWidth), textX, float64(y+1), titleColor)
+	details := songDetails(entry)
+	if selected && s.settings.FailMode {
+		details += "  FAIL"
+	}
+	DrawText(screen, FitText(details, textWidth), textX, float64(y+1+uiLineHeight), cfg.Colors.Medium)
+	DrawText(screen, FitText(s.chartDetails(entry, chart), textWidth), textX, float64(y+1+uiLineHeight*2), titleColor)
+}
+
+// songDetails formats the BPM and duration of a song.
+func songDetails(entry *gamesongs.Entry) string {
+	seconds := int(entry.Duration)
+	return fmt.Sprintf("%dBPM %d:%02d", entry.Bpm, seconds/60, seconds%60)
+}
+
+// chartDetails formats the difficulty, level and best score of a chart.
+func (s *TrackSelectionScene) chartDetails(entry *gamesongs.Entry, chart gamesongs.ChartInfo) string {
+	details := chart.Difficulty
+	if chart.Level > 0 {
+		details += fmt.Sprintf(" %d", chart.Level)
+	}
+	if best, ok := s.records.Best(entry.ID, chart.Difficulty); ok {
+		details += fmt.Sprintf(" HI%06d", best.BestScore)
+	}
+	return details
+}
+
+func (s *TrackSelectionScene) play() {
+	s.DisableKeys()
+	s.selection.Song = s.songs.All()[s.cursor]
+	s.Manager.NavigateTo(ScenePlay, transition.NewFader(), true)
+}
+
+// tap selects the tapped song. Tapping the selected song plays it, or changes
+// its difficulty when the chart line is tapped.
+func (s *TrackSelectionScene) tap(p image.Point) {
+	row := (p.Y - trackListMargin) / (trackRowHeight + trackRowGap)
+	rowY := (p.Y - trackListMargin) % (trackRowHeight + trackRowGap)
+	i := s.scroll + row
+	if p.Y < trackListMargin || row >= s.visibleRows() || rowY >= trackRowHeight || i >= s.songs.Len() {
+		return
+	}
+
+	switch {
+	case i != s.cursor:
+		s.moveCursor(i)
+	case rowY > 1+uiLineHeight*2:
+		s.cycleDifficulty()
+	default:
+		s.play()
+	}
+}
+
+// cycleDifficulty moves to the next chart of the highlighted song, back to the
+// first one after the last.
+func (s *TrackSelectionScene) cycleDifficulty() {
+	entry := s.songs.All()[s.cursor]
+	current := entry.Chart(s.selection.Difficulty)
+	s.changeDifficulty(1)
+	if entry.Chart(s.selection.Difficulty).Difficulty == current.Difficulty && len(entry.Charts) > 0 {
+		s.selection.Difficulty = entry.Charts[0].Difficulty
+	}
+}
+
+// watchReplay plays back the last replay of the highlighted chart, if any.
+func (s *TrackSelectionScene) watchReplay() {
+	entry := s.songs.All()[s.cursor]
+	chart := entry.Chart(s.selection.Difficulty)
+
+	replay, err := gamereplay.Load(s.AppContext.SaveManager, entry.ID, chart.Difficulty)
+	if err != nil {
+		log.Printf("failed to load replay: %v", err)
+		return
+	}
+
+	s.DisableKeys()
+	s.selection.Song = entry
+	s.selection.Replay = replay
+	s.Manager.NavigateTo(ScenePlay, transition.NewFader(), true)
+}
+
+// changeDifficulty moves to the previous or next chart of the highlighted song.
+func (s *TrackSelectionScene) changeDifficulty(step int) {
+	if s.songs.Len() == 0 {
+		return
+	}
+
+	entry := s.songs.All()[s.cursor]
+	current := entry.Chart(s.selection.Difficulty)
+	for i, c := range entry.Charts {
+		if c.Difficulty == current.Difficulty && i+step >= 0 && i+step < len(entry.Charts) {
+			s.selection.Difficulty = entry.Charts[i+step].Difficulty
+			return
+		}
+	}
+}
+
+func (s *TrackSelectionScene) visibleRows() int {
+	height := config.Get().ScreenHeight - trackListMargin*2 + trackRowGap
+	return height / (trackRowHeight + trackRowGap)
+}
+
+func (s *TrackSelectionScene) moveCursor(cursor int) {
+	if cursor < 0 || cursor >= s.songs.Len() {
+		return
+	}
+
+	s.cursor = cursor
+	if s.cursor < s.scroll {
+		s.scroll = s.cursor
+	}
+	if s.cursor >= s.scroll+s.visibleRows() {
+		s.scroll = s.cursor - s.visibleRows() + 1
+	}
+
+	s.stopPreview()
+	s.previewTimer = 0
+}
+
+func (s *TrackSelectionScene) startPreview() {
+	if s.songs.Len() == 0 {
+		return
+	}
+
+	entry := s.songs.All()[s.cursor]
+	start := time.Duration(entry.PreviewStart * float64(time.Second))
+	if start == 0 {
+		// Songs usually take a while to get going
+		start = time.Duration(entry.Duration * 0.3 * float64(time.Second))
+	}
+
+	s.preview = entry.AudioPath()
+	s.audiomanager.PlayFrom(s.preview, start)
+}
+
+func (s *TrackSelectionScene) stopPreview() {
+	if s.preview == "" {
+		return
+	}
+
+	s.audiomanager.PauseMusic(s.preview)
+	s.preview = ""
 }
@@ -0,0 +1,38 @@
+package gamescene
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"golang.org/x/image/font/basicfont"
+)
+
+// uiFace is the bitmap font used for menu and list text.
+var uiFace = text.NewGoXFace(basicfont.Face7x13)
+
+const (
+	uiCharWidth  = 7
+	uiLineHeight = 13
+)
+
+// DrawText draws str with its top-left corner at x, y.
+func DrawText(screen *ebiten.Image, str string, x, y float64, c color.Color) {
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(x, y)
+	op.ColorScale.ScaleWithColor(c)
+	text.Draw(screen, str, uiFace, op)
+}
+
+// FitText cuts str so it is at most width pixels wide.
+func FitText(str string, width int) string {
+	maxChars := width / uiCharWidth
+	runes := []rune(str)
+	if len(runes) <= maxChars {
+		return str
+	}
+	if maxChars <= 1 {
+		return string(runes[:max(maxChars, 0)])
+	}
+	return string(runes[:maxChars-1]) + "."
+}
@@ -13,18 +13,34 @@ import (
 	"github.com/leandroatallah/drummer/internal/engine/actors"
 	"github.com/leandroatallah/drummer/internal/engine/core"
 	"github.com/leandroatallah/drummer/internal/engine/core/game"
+	"github.com/leandroatallah/drummer/internal/engine/core/game/state"
 	"github.com/leandroatallah/drummer/internal/engine/core/levels"
 	"github.com/leandroatallah/drummer/internal/engine/core/scene"
 	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
 	"github.com/leandroatallah/drummer/internal/engine/systems/datamanager"
 	"github.com/leandroatallah/drummer/internal/engine/systems/imagemanager"
 	"github.com/leandroatallah/drummer/internal/engine/systems/input"
+	"github.com/leandroatallah/drummer/internal/engine/systems/savemanager"
+	gameplay "github.com/leandroatallah/drummer/internal/game/play"
 	gamescene "github.com/leandroatallah/drummer/internal/game/scenes"
+	gamesongs "github.com/leandroatallah/drummer/internal/game/songs"
+	gamestate "github.com/leandroatallah/drummer/internal/game/state"
+	gametheme "github.com/leandroatallah/drummer/internal/game/theme"
 )
 
+// uiDuckLevel is how loud the music is while a menu sound plays.
+const uiDuckLevel = 0.4
+
+// soundBuses are the audio files that are not music.
+var soundBuses = map[string]audiomanager.Bus{
+	"jab8.wav":         audiomanager.BusSFX,
+	"jab8.ogg":         audiomanager.BusUI,
+	"kick_backOGG.ogg": audiomanager.BusSFX,
+}
+
 func Setup(assets fs.FS) {
 	// Basic Ebiten setup
-	ebiten.SetWindowSize(config.Get().ScreenWidth*6, config.Get().ScreenHeight*6)
+	ebiten.SetWindowSize(config.Get().ScreenWidth*config.Get().WindowScale, config.Get().ScreenHeight*config.Get().WindowScale)
 	ebiten.SetWindowTitle("The Drummer")
 
 	// Initialize all systems and managers
@@ -32,6 +48,7 @@ func Setup(assets fs.FS) {
 	audioManager := audiomanager.NewAudioManager()
 	imageManager := imagemanager.NewImageManager()
 	dataManager := datamanager.NewDataManager()
+	saveManager := savemanager.NewSaveManager("drummer")
 	sceneManager := scene.NewSceneManager()
 	levelManager := levels.NewManager()
 	actorManager := actors.NewManager()
@@ -41,11 +58,24 @@ func Setup(assets fs.FS) {
 	loadImageAssetsFromFS(assets, imageManager)
 	loadDataAssetsFromFS(assets, dataManager)
 
+	songRegistry := gamesongs.NewRegistry()
+	songRegistry.SetValidator(gameplay.ValidateSong)
+	if err := songRegistry.LoadFromFS(assets, gamesongs.SongsDir, audioManager); err != nil {
+		log.Fatalf("error reading songs dir: %v", err)
+	}
+	songRegistry.LoadOverrides(saveManager)
+
+	themes := gametheme.NewRegistry()
+	if err := themes.LoadFromFS(assets, gametheme.ThemesDir); err != nil {
+		log.Printf("error reading themes dir: %v", err)
+	}
+
 	appContext := &core.AppContext{
 		InputManager:    inputManager,
 		AudioManager:    audioManager,
 		ImageManager:    imageManager,
 		DataManager:     dataManager,
+		SaveManager:     saveManager,
 		DialogueManager: nil,
 		ActorManager:    actorManager,
 		SceneManager:    sceneManager,
@@ -54,7 +84,7 @@ func Setup(assets fs.FS) {
 		Assets: assets,
 	}
 
-	sceneFactory := scene.NewDefaultSceneFactory(gamescene.InitSceneMap(appContext))
+	sceneFactory := scene.NewDefaultSceneFactory(gamescene.InitSceneMap(appContext, songRegistry, themes))
 	sceneFactory.SetAppContext(appContext)
 
 	sceneManager.SetFactory(sceneFactory)
@@ -62,6 +92,12 @@ func Setup(assets fs.FS) {
 
 	// Create and run the game
 	game := game.NewGame(appContext)
+	game.SetScreenFilter(themes)
+	game.SetStateFactory(state.NewDefaultSceneFactory(gamestate.NewStateMap(appContext)))
+	appContext.StateManager = game
+	if err := game.SetState(gamestate.MainMenu); err != nil {
+		log.Fatal(err)
+	}
 
 	// Set initial game scene
 	game.AppContext.SceneManager.NavigateTo(gamescene.SceneMenu, nil, false)
@@ -97,9 +133,14 @@ func loadAudioAssetsFromFS(assets fs.FS, am *audiomanager.AudioManager) {
 			continue
 		}
 
-		// Use the existing Add method to process and store the player.
-		am.Add(dir+"/"+fileName, data)
+		bus, ok := soundBuses[fileName]
+		if !ok {
+			bus = audiomanager.BusMusic
+		}
+		am.AddTo(bus, dir+"/"+fileName, data)
 	}
+
+	am.SetDucking(audiomanager.BusUI, audiomanager.BusMusic, uiDuckLevel)
 }
 
 // loadImageAssetsFromFS is a helper function to load all images files from an fs.FS.
@@ -1,6 +0,0 @@
-package gamesetup
-
-var songFiles = []string{
-	"black-sabbath-paranoid.ogg",
-	"smell-like-teen-spirit.ogg",
-}
@@ -0,0 +1,272 @@
+package gamesongs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Difficulty names, from the easiest chart to the hardest one.
+const (
+	DifficultyEasy   = "Easy"
+	DifficultyNormal = "Normal"
+	DifficultyHard   = "Hard"
+	DifficultyExpert = "Expert"
+)
+
+// Difficulties lists the difficulty names in order.
+var Difficulties = []string{DifficultyEasy, DifficultyNormal, DifficultyHard, DifficultyExpert}
+
+// ChartInfo is the metadata of one difficulty chart of a song.
+type ChartInfo struct {
+	Difficulty string `json:"difficulty"`
+	Level      int    `json:"level,omitempty"`
+}
+
+// Entry is the metadata of a song file found in the song library.
+type Entry struct {
+	ID       string  `json:"-"`
+	Title    string  `json:"title"`
+	Artist   string  `json:"artist,omitempty"`
+	Filename string  `json:"filename"`
+	Bpm      int     `json:"bpm"`
+	Duration float64 `json:"duration"`
+	// Difficulty is the level of songs with a single top-level "notes" chart.
+	Difficulty int         `json:"difficulty,omitempty"`
+	Charts     []ChartInfo `json:"charts,omitempty"`
+	// PreviewStart is where the track selection preview starts, in seconds.
+	PreviewStart float64 `json:"preview_start,omitempty"`
+
+	// Data is the raw chart JSON, parsed again by the play scene.
+	Data []byte `json:"-"`
+}
+
+// AudioPath is the AudioManager name of the song audio.
+func (e *Entry) AudioPath() string {
+	return AudioDir + "/" + e.Filename
+}
+
+// Chart returns the chart of a difficulty. When the song has no such chart,
+// the closest difficulty is returned instead.
+func (e *Entry) Chart(difficulty string) ChartInfo {
+	rank := DifficultyRank(difficulty)
+	closest := e.Charts[0]
+	for _, c := range e.Charts {
+		if c.Difficulty == difficulty {
+			return c
+		}
+		if abs(DifficultyRank(c.Difficulty)-rank) < abs(DifficultyRank(closest.Difficulty)-rank) {
+			closest = c
+		}
+	}
+	return closest
+}
+
+// DifficultyRank returns the position of a difficulty name in Difficulties.
+// Unknown names rank after Expert.
+func DifficultyRank(difficulty string) int {
+	for i, d := range Difficulties {
+		if strings.EqualFold(d, difficulty) {
+			return i
+		}
+	}
+	return len(Difficulties)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// AudioChecker is implemented by the audio manager to tell if a sound was loaded.
+type AudioChecker interface {
+	Has(name string) bool
+}
+
+// Validator checks the notes, lanes and events of a song file, which
+// ParseEntry doesn't read. The play package implements it, as it parses the
+// charts and imports this package.
+type Validator func(data []byte) error
+
+const (
+	SongsDir = "assets/songs"
+	AudioDir = "assets/audio"
+)
+
+// Registry holds every playable song chart.
+type Registry struct {
+	entries  []*Entry
+	byID     map[string]*Entry
+	validate Validator
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byID: make(map[string]*Entry)}
+}
+
+// SetValidator makes the registry leave out the songs that validate rejects,
+// so they are never listed.
+func (r *Registry) SetValidator(validate Validator) {
+	r.validate = validate
+}
+
+// LoadFromFS scans dir for song charts. Charts that can't be parsed or
+// validated, or whose audio file is missing, are skipped.
+func (r *Registry) LoadFromFS(assets fs.FS, dir string, audio AudioChecker) error {
+	files, err := fs.ReadDir(assets, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		data, err := fs.ReadFile(assets, path.Join(dir, file.Name()))
+		if err != nil {
+			log.Printf("failed to read song chart %s: %v", file.Name(), err)
+			continue
+		}
+
+		entry, err := r.parse(file.Name(), data)
+		if err != nil {
+			log.Printf("invalid song chart %s: %v", file.Name(), err)
+			continue
+		}
+
+		if audio != nil && !audio.Has(entry.AudioPath()) {
+			log.Printf("song chart %s: audio not found: %s", file.Name(), entry.AudioPath())
+			continue
+		}
+
+		r.Add(entry)
+	}
+
+	return nil
+}
+
+// ParseEntry reads the metadata of a song chart.
+func ParseEntry(id string, data []byte) (*Entry, error) {
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Filename == "" {
+		return nil, fmt.Errorf("missing audio filename")
+	}
+	if entry.Bpm <= 0 {
+		return nil, fmt.Errorf("invalid bpm: %d", entry.Bpm)
+	}
+	if entry.Title == "" {
+		entry.Title = strings.TrimSuffix(id, ".json")
+	}
+	if len(entry.Charts) == 0 {
+		// Songs with a single chart keep their notes at the top level.
+		entry.Charts = []ChartInfo{{Difficulty: DifficultyNormal, Level: entry.Difficulty}}
+	}
+	sort.SliceStable(entry.Charts, func(i, j int) bool {
+		return DifficultyRank(entry.Charts[i].Difficulty) < DifficultyRank(entry.Charts[j].Difficulty)
+	})
+
+	entry.ID = id
+	entry.Data = data
+	return &entry, nil
+}
+
+// parse reads the metadata of a song chart, once the validator accepts it.
+func (r *Registry) parse(id string, data []byte) (*Entry, error) {
+	entry, err := ParseEntry(id, data)
+	if err != nil {
+		return nil, err
+	}
+	if r.validate != nil {
+		if err := r.validate(data); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// Loader reads files saved by the chart editor.
+type Loader interface {
+	Load(name string) ([]byte, error)
+}
+
+// EditedChartName is the save file name of a song edited in the chart editor.
+// The song files are embedded in the game, so the editor can't write them:
+// edits are kept in the save storage instead, as a whole song file that can be
+// copied over the one in SongsDir.
+func EditedChartName(id string) string {
+	return "chart-" + id
+}
+
+// LoadOverrides replaces songs with the versions saved by the chart editor.
+// They overlay the embedded song files until they are removed from the save
+// storage.
+func (r *Registry) LoadOverrides(storage Loader) {
+	// Update sorts the entries, so a retitled song would move under the loop.
+	for _, entry := range append([]*Entry(nil), r.entries...) {
+		data, err := storage.Load(EditedChartName(entry.ID))
+		if err != nil {
+			continue
+		}
+		if err := r.Update(entry.ID, data); err != nil {
+			log.Printf("invalid edited chart %s: %v", entry.ID, err)
+		}
+	}
+}
+
+// Update replaces the chart data of a song already in the registry, keeping
+// the list sorted by title.
+func (r *Registry) Update(id string, data []byte) error {
+	entry, ok := r.byID[id]
+	if !ok {
+		return fmt.Errorf("unknown song: %s", id)
+	}
+
+	updated, err := r.parse(id, data)
+	if err != nil {
+		return err
+	}
+
+	*entry = *updated
+	r.sort()
+	return nil
+}
+
+// Add stores an entry, keeping the list sorted by title.
+func (r *Registry) Add(entry *Entry) {
+	if _, ok := r.byID[entry.ID]; ok {
+		return
+	}
+
+	r.byID[entry.ID] = entry
+	r.entries = append(r.entries, entry)
+	r.sort()
+}
+
+func (r *Registry) sort() {
+	sort.SliceStable(r.entries, func(i, j int) bool {
+		return r.entries[i].Title < r.entries[j].Title
+	})
+}
+
+func (r *Registry) All() []*Entry {
+	return r.entries
+}
+
+func (r *Registry) Len() int {
+	return len(r.entries)
+}
+
+func (r *Registry) Get(id string) (*Entry, bool) {
+	entry, ok := r.byID[id]
+	return entry, ok
+}
@@ -0,0 +1,96 @@
+package gamesongs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/leandroatallah/drummer/internal/engine/systems/savemanager"
+)
+
+func songJSON(title string) []byte {
+	return []byte(fmt.Sprintf(`{"title": %q, "filename": "song.ogg", "bpm": 120, "notes": []}`, title))
+}
+
+func titles(r *Registry) []string {
+	var titles []string
+	for _, e := range r.All() {
+		titles = append(titles, e.Title)
+	}
+	return titles
+}
+
+func TestRegistryUpdateKeepsOrder(t *testing.T) {
+	r := NewRegistry()
+	for id, title := range map[string]string{"a.json": "Alpha", "b.json": "Bravo", "c.json": "Charlie"} {
+		entry, err := ParseEntry(id, songJSON(title))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Add(entry)
+	}
+
+	if err := r.Update("a.json", songJSON("Delta")); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(titles(r)); got != "[Bravo Charlie Delta]" {
+		t.Errorf("titles = %s, want [Bravo Charlie Delta]", got)
+	}
+	if entry, _ := r.Get("a.json"); entry.Title != "Delta" {
+		t.Errorf("entry title = %s, want Delta", entry.Title)
+	}
+
+	if err := r.Update("z.json", songJSON("Zulu")); err == nil {
+		t.Error("updated an unknown song")
+	}
+}
+
+func TestRegistryValidator(t *testing.T) {
+	assets := fstest.MapFS{
+		"songs/good.json":   {Data: songJSON("Good")},
+		"songs/broken.json": {Data: songJSON("Broken")},
+	}
+	r := NewRegistry()
+	r.SetValidator(func(data []byte) error {
+		if strings.Contains(string(data), "Broken") {
+			return errors.New("broken chart")
+		}
+		return nil
+	})
+	if err := r.LoadFromFS(assets, "songs", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fmt.Sprint(titles(r)); got != "[Good]" {
+		t.Errorf("titles = %s, want the broken song left out", got)
+	}
+	if err := r.Update("good.json", songJSON("Broken")); err == nil {
+		t.Error("updated a song with a broken chart")
+	}
+	if entry, _ := r.Get("good.json"); entry.Title != "Good" {
+		t.Errorf("entry title = %s, want the song kept as it was", entry.Title)
+	}
+}
+
+func TestRegistryLoadOverrides(t *testing.T) {
+	r := NewRegistry()
+	for id, title := range map[string]string{"a.json": "Alpha", "b.json": "Bravo", "c.json": "Charlie"} {
+		entry, err := ParseEntry(id, songJSON(title))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Add(entry)
+	}
+
+	// The first override moves its song after the second one.
+	r.LoadOverrides(savemanager.MemoryStore{
+		EditedChartName("a.json"): songJSON("Delta"),
+		EditedChartName("b.json"): songJSON("Bravo Edit"),
+	})
+
+	if got := fmt.Sprint(titles(r)); got != "[Bravo Edit Charlie Delta]" {
+		t.Errorf("titles = %s, want both overrides loaded", got)
+	}
+}
@@ -1,6 +1,9 @@
 package gamestate
 
-import "github.com/leandroatallah/drummer/internal/engine/core/game/state"
+import (
+	"github.com/leandroatallah/drummer/internal/engine/core"
+	"github.com/leandroatallah/drummer/internal/engine/core/game/state"
+)
 
 const (
 	Intro state.GameStateEnum = iota
@@ -9,3 +12,24 @@ const (
 	Paused
 	GameOver
 )
+
+// NewStateMap returns the states of the game, with access to its systems.
+func NewStateMap(ctx *core.AppContext) state.StateMap {
+	intro := &IntroState{}
+	mainMenu := &MainMenuState{}
+	playing := &PlayingState{}
+	paused := &PausedState{}
+	gameOver := &GameOverState{}
+
+	for _, s := range []interface{ SetAppContext(*core.AppContext) }{intro, mainMenu, playing, paused, gameOver} {
+		s.SetAppContext(ctx)
+	}
+
+	return state.StateMap{
+		Intro:    intro,
+		MainMenu: mainMenu,
+		Playing:  playing,
+		Paused:   paused,
+		GameOver: gameOver,
+	}
+}
@@ -1,9 +1,23 @@
 package gamestate
 
-import "github.com/leandroatallah/drummer/internal/engine/core/game/state"
+import (
+	"time"
 
+	"github.com/leandroatallah/drummer/internal/engine/core/game/state"
+	"github.com/leandroatallah/drummer/internal/engine/systems/audiomanager"
+)
+
+// gameOverFade is how long the music takes to fade out when a song fails.
+const gameOverFade = time.Second
+
+// GameOverState is entered when the life gauge runs out in fail mode. The
+// music bus fades out while the failure plays out.
 type GameOverState struct {
 	state.BaseState
 }
 
-func (s *GameOverState) OnStart() {}
+func (s *GameOverState) OnStart() {
+	if ctx := s.AppContext(); ctx != nil {
+		ctx.AudioManager.FadeOutBus(audiomanager.BusMusic, gameOverFade)
+	}
+}
@@ -2,8 +2,14 @@ package gamestate
 
 import "github.com/leandroatallah/drummer/internal/engine/core/game/state"
 
+// PausedState is entered while a song is paused. Scenes keep running to show
+// their pause menu, but every sound of the audio manager stops.
 type PausedState struct {
 	state.BaseState
 }
 
-func (s *PausedState) OnStart() {}
+func (s *PausedState) OnStart() {
+	if ctx := s.AppContext(); ctx != nil {
+		ctx.AudioManager.PauseAll()
+	}
+}
@@ -0,0 +1,191 @@
+package gametheme
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/leandroatallah/drummer/internal/config"
+)
+
+const (
+	ThemesDir = "assets/themes"
+	// DefaultTheme is the palette the assets are drawn with.
+	DefaultTheme = "olive"
+)
+
+// sourceTones are the four tones of the assets, from the lightest to the
+// darkest. The first three are the config colors, so what the scenes fill
+// with them is recolored like the assets.
+var sourceTones = [4]color.RGBA{
+	config.Get().Colors.Light,
+	config.Get().Colors.Medium,
+	config.Get().Colors.Dark,
+	{45, 57, 32, 255},
+}
+
+//go:embed palette.kage
+var paletteShaderSrc []byte
+
+// Palette is a theme: four tones that replace the source tones, from the
+// lightest to the darkest.
+type Palette struct {
+	ID    string    `json:"-"`
+	Name  string    `json:"name"`
+	Tones [4]string `json:"tones"`
+
+	colors [4]color.RGBA
+	// uniform holds the tones as the shader reads them.
+	uniform []float32
+}
+
+// ParsePalette reads a theme file. The theme ID is the file name without its
+// extension.
+func ParsePalette(fileName string, data []byte) (*Palette, error) {
+	var p Palette
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	p.ID = strings.TrimSuffix(fileName, path.Ext(fileName))
+	if p.Name == "" {
+		p.Name = p.ID
+	}
+	for i, tone := range p.Tones {
+		c, err := parseHex(tone)
+		if err != nil {
+			return nil, fmt.Errorf("tone %d: %w", i, err)
+		}
+		p.colors[i] = c
+	}
+	p.uniform = toneUniform(p.colors)
+	return &p, nil
+}
+
+// parseHex reads a color written as #rrggbb.
+func parseHex(s string) (color.RGBA, error) {
+	var r, g, b uint8
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{r, g, b, 255}, nil
+}
+
+// Registry holds the themes, and recolors the screen with the current one.
+type Registry struct {
+	palettes []*Palette
+	current  *Palette
+
+	shader *ebiten.Shader
+	op     ebiten.DrawRectShaderOptions
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// LoadFromFS reads the theme files of dir. Themes that can't be parsed are
+// skipped.
+func (r *Registry) LoadFromFS(assets fs.FS, dir string) error {
+	files, err := fs.ReadDir(assets, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		data, err := fs.ReadFile(assets, path.Join(dir, file.Name()))
+		if err != nil {
+			log.Printf("failed to read theme %s: %v", file.Name(), err)
+			continue
+		}
+
+		p, err := ParsePalette(file.Name(), data)
+		if err != nil {
+			log.Printf("invalid theme %s: %v", file.Name(), err)
+			continue
+		}
+		r.palettes = append(r.palettes, p)
+	}
+
+	// The default theme comes first.
+	sort.SliceStable(r.palettes, func(i, j int) bool {
+		if r.palettes[i].ID == DefaultTheme || r.palettes[j].ID == DefaultTheme {
+			return r.palettes[i].ID == DefaultTheme
+		}
+		return r.palettes[i].ID < r.palettes[j].ID
+	})
+	return nil
+}
+
+// Palettes returns the themes, the default one first.
+func (r *Registry) Palettes() []*Palette {
+	return r.palettes
+}
+
+// Current is the theme on screen, or nil when the assets keep their tones.
+func (r *Registry) Current() *Palette {
+	return r.current
+}
+
+// Set switches to the theme with the given ID, or to the default theme for an
+// empty ID. Unknown IDs keep the assets tones, and report false.
+func (r *Registry) Set(id string) bool {
+	if id == "" {
+		id = DefaultTheme
+	}
+	for _, p := range r.palettes {
+		if p.ID == id {
+			r.current = p
+			return true
+		}
+	}
+	r.current = nil
+	return false
+}
+
+// Apply draws the frame in src on dst, in the tones of the current theme.
+func (r *Registry) Apply(dst, src *ebiten.Image) {
+	if r.current == nil || r.current.colors == sourceTones {
+		dst.DrawImage(src, nil)
+		return
+	}
+
+	if r.shader == nil {
+		shader, err := ebiten.NewShader(paletteShaderSrc)
+		if err != nil {
+			log.Printf("failed to compile the palette shader: %v", err)
+			r.current = nil
+			dst.DrawImage(src, nil)
+			return
+		}
+		r.shader = shader
+		r.op.Uniforms = map[string]any{"From": toneUniform(sourceTones)}
+	}
+
+	r.op.Uniforms["To"] = r.current.uniform
+	r.op.Images[0] = src
+	bounds := src.Bounds()
+	dst.DrawRectShader(bounds.Dx(), bounds.Dy(), r.shader, &r.op)
+}
+
+// toneUniform lays tones out as the vec4 array of the shader.
+func toneUniform(tones [4]color.RGBA) []float32 {
+	u := make([]float32, 0, 16)
+	for _, c := range tones {
+		u = append(u, float32(c.R)/0xff, float32(c.G)/0xff, float32(c.B)/0xff, 1)
+	}
+	return u
+}
@@ -0,0 +1,75 @@
+package gametheme
+
+import (
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestParseHex(t *testing.T) {
+	valid := map[string]color.RGBA{
+		"#000000": {0, 0, 0, 255},
+		"#9db36b": {157, 179, 107, 255},
+		"#FFFFFF": {255, 255, 255, 255},
+	}
+	for s, want := range valid {
+		got, err := parseHex(s)
+		if err != nil || got != want {
+			t.Errorf("parseHex(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"", "9db36b", "#9db36", "#9db36b0", "#9db3zz", "#fff"} {
+		if _, err := parseHex(s); err == nil {
+			t.Errorf("parseHex(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestParsePalette(t *testing.T) {
+	p, err := ParsePalette("night.json", []byte(`{"tones": ["#ffffff", "#aaaaaa", "#555555", "#000000"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != "night" || p.Name != "night" {
+		t.Errorf("ID, Name = %q, %q, want the file name", p.ID, p.Name)
+	}
+	if p.colors[1] != (color.RGBA{0xaa, 0xaa, 0xaa, 255}) {
+		t.Errorf("second tone = %v", p.colors[1])
+	}
+	if len(p.uniform) != 16 || p.uniform[0] != 1 || p.uniform[12] != 0 {
+		t.Errorf("uniform = %v", p.uniform)
+	}
+
+	p, err = ParsePalette("night.json", []byte(`{"name": "Night", "tones": ["#ffffff", "#aaaaaa", "#555555", "#000000"]}`))
+	if err != nil || p.Name != "Night" {
+		t.Errorf("Name = %q, %v, want Night", p.Name, err)
+	}
+
+	invalid := map[string]string{
+		"missing tones": `{"tones": ["#ffffff", "#aaaaaa"]}`,
+		"invalid tone":  `{"tones": ["#ffffff", "gray", "#555555", "#000000"]}`,
+		"not json":      `tones`,
+	}
+	for name, data := range invalid {
+		if _, err := ParsePalette("bad.json", []byte(data)); err == nil {
+			t.Errorf("%s: parsed, want an error", name)
+		}
+	}
+}
+
+func TestThemeData(t *testing.T) {
+	files, err := os.ReadDir("../../../" + ThemesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile("../../../" + ThemesDir + "/" + file.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParsePalette(file.Name(), data); err != nil {
+			t.Errorf("invalid theme %s: %v", file.Name(), err)
+		}
+	}
+}